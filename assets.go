@@ -0,0 +1,236 @@
+package revel
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This object fingerprints the app's public files with a hash of their
+// contents, so that they may be cached by clients forever:
+//
+//   {{asset "css/app.css"}}  =>  /public/css/app.0cc175b9c0.css
+//
+// The fingerprinted URL changes whenever the file does.  In dev mode, the
+// fingerprints are recomputed when the watcher sees changes.
+//
+// Bundles of assets may be declared in app.conf, to be concatenated and served
+// as a single asset:
+//
+//   assets.bundle.js/all.js = js/jquery.js, js/app.js
+type AssetManager struct {
+	// The directory of the assets, e.g. BasePath/public.
+	path string
+	// The URL at which the directory is served, e.g. /public.
+	urlPrefix string
+	// Map from the names of the bundles to the names of their assets.
+	bundles map[string][]string
+
+	mutex sync.RWMutex
+	// Map from asset name (e.g. "css/app.css") to the fingerprinted name.
+	fingerprints map[string]string
+	// Map from fingerprinted name back to the asset name.
+	assets map[string]string
+	// Map from bundle name to the concatenated contents of its assets.
+	bundleContents map[string]string
+	// The time of the last refresh, used as the ModTime of bundles.
+	refreshed time.Time
+}
+
+var MainAssets *AssetManager
+
+// The value of the Cache-Control header for fingerprinted assets.
+const AssetCacheControl = "public, max-age=31536000, immutable"
+
+func NewAssetManager(path, urlPrefix string, bundles map[string][]string) *AssetManager {
+	return &AssetManager{
+		path:           path,
+		urlPrefix:      strings.TrimRight(urlPrefix, "/"),
+		bundles:        bundles,
+		fingerprints:   map[string]string{},
+		assets:         map[string]string{},
+		bundleContents: map[string]string{},
+	}
+}
+
+// Create the asset manager for the app, per app.conf:
+//   assets.path = public        (relative to the app's base path)
+//   assets.url = /public        (the route serving that directory)
+//   assets.bundle.<name> = <asset>, <asset>, ...
+func newAppAssetManager() *AssetManager {
+	bundles := map[string][]string{}
+	for _, key := range Config.Options("assets.bundle.") {
+		var names []string
+		for _, name := range strings.Split(Config.StringDefault(key, ""), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		bundles[key[len("assets.bundle."):]] = names
+	}
+
+	return NewAssetManager(
+		filepath.Join(BasePath, filepath.FromSlash(Config.StringDefault("assets.path", "public"))),
+		Config.StringDefault("assets.url", "/public"),
+		bundles)
+}
+
+// Refresh recomputes the fingerprints of all the assets and bundles.
+func (m *AssetManager) Refresh() *Error {
+	TRACE.Printf("Refreshing assets from %s", m.path)
+	fingerprints := map[string]string{}
+	assets := map[string]string{}
+	bundleContents := map[string]string{}
+
+	// Map from asset name to the hash of its contents.
+	hashes := map[string]string{}
+	if DirExists(m.path) {
+		err := WalkFiles(m.path, func(fileName string, info os.FileInfo, err error) error {
+			if err != nil {
+				ERROR.Println("error walking assets:", err)
+				return nil
+			}
+			if info.IsDir() {
+				if strings.HasPrefix(info.Name(), ".") && fileName != m.path {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasPrefix(info.Name(), ".") {
+				return nil
+			}
+
+			hash, err := hashFile(fileName)
+			if err != nil {
+				ERROR.Println("Failed reading asset:", fileName)
+				return nil
+			}
+			hashes[filepath.ToSlash(fileName[len(m.path)+1:])] = hash
+			return nil
+		})
+		if err != nil {
+			return &Error{
+				Title:       "Failed to load assets",
+				Description: err.Error(),
+			}
+		}
+	}
+
+	for bundle, names := range m.bundles {
+		var b bytes.Buffer
+		for _, name := range names {
+			if _, ok := hashes[name]; !ok {
+				return &Error{
+					Title:       "Asset bundle error",
+					Description: fmt.Sprintf("Asset %s of bundle %s not found in %s", name, bundle, m.path),
+				}
+			}
+			assetBytes, err := ReadFile(filepath.Join(m.path, filepath.FromSlash(name)))
+			if err != nil {
+				return &Error{
+					Title:       "Asset bundle error",
+					Description: fmt.Sprintf("Failed reading asset %s of bundle %s: %s", name, bundle, err),
+				}
+			}
+			b.Write(assetBytes)
+			if len(assetBytes) > 0 && assetBytes[len(assetBytes)-1] != '\n' {
+				b.WriteByte('\n')
+			}
+		}
+		bundleContents[bundle] = b.String()
+		hashes[bundle] = fmt.Sprintf("%x", md5.Sum(b.Bytes()))
+	}
+
+	for name, hash := range hashes {
+		fingerprinted := fingerprintName(name, hash)
+		fingerprints[name] = fingerprinted
+		assets[fingerprinted] = name
+	}
+
+	m.mutex.Lock()
+	m.fingerprints, m.assets, m.bundleContents = fingerprints, assets, bundleContents
+	m.refreshed = time.Now()
+	m.mutex.Unlock()
+	return nil
+}
+
+// Return the hex MD5 hash of the file's contents, read a block at a time.
+func hashFile(fileName string) (string, error) {
+	file, err := OpenFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := md5.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// Return the name of the asset with (the beginning of) the hash of its
+// contents inserted before the extension, e.g. "css/app.0cc175b9c0.css".
+func fingerprintName(name, hash string) string {
+	hash = hash[:10]
+	ext := path.Ext(name)
+	if ext == "" || strings.Contains(ext, "/") {
+		return name + "." + hash
+	}
+	return name[:len(name)-len(ext)] + "." + hash + ext
+}
+
+// Return the fingerprinted URL of the named asset.
+// If it is not known, its URL is returned without a fingerprint.
+func (m *AssetManager) Url(name string) string {
+	name = strings.TrimLeft(name, "/")
+	m.mutex.RLock()
+	fingerprinted, ok := m.fingerprints[name]
+	m.mutex.RUnlock()
+	if !ok {
+		WARN.Printf("Asset %s not found in %s", name, m.path)
+		fingerprinted = name
+	}
+	return m.urlPrefix + "/" + fingerprinted
+}
+
+// Open the file at the given path, if it names a fingerprinted asset or a
+// bundle, and report whether it was fingerprinted.  Returns os.ErrNotExist for
+// other paths (which may still be plain files in the assets directory).
+func (m *AssetManager) Open(fileName string) (file http.File, fingerprinted bool, err error) {
+	if !strings.HasPrefix(fileName, m.path+string(filepath.Separator)) {
+		return nil, false, os.ErrNotExist
+	}
+	name := filepath.ToSlash(fileName[len(m.path)+1:])
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if asset, ok := m.assets[name]; ok {
+		name, fingerprinted = asset, true
+	}
+	if contents, ok := m.bundleContents[name]; ok {
+		info := &memoryFileInfo{path.Base(name), int64(len(contents)), m.refreshed, false}
+		return &memoryFile{strings.NewReader(contents), info}, fingerprinted, nil
+	}
+	if !fingerprinted {
+		return nil, false, os.ErrNotExist
+	}
+	file, err = OpenFile(filepath.Join(m.path, filepath.FromSlash(name)))
+	return file, true, err
+}
+
+// Return the fingerprinted URL of the named asset, e.g.
+// {{asset "css/app.css"}} => /public/css/app.0cc175b9c0.css
+func assetUrl(name string) string {
+	if MainAssets == nil {
+		return "/public/" + strings.TrimLeft(name, "/")
+	}
+	return MainAssets.Url(name)
+}
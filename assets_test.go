@@ -0,0 +1,73 @@
+package revel
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAssetFingerprints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "css"), 0755)
+	os.MkdirAll(filepath.Join(dir, "js"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "css", "app.css"), []byte("body {}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "js", "a.js"), []byte("var a;"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "js", "b.js"), []byte("var b;\n"), 0644)
+
+	assets := NewAssetManager(dir, "/public/", map[string][]string{
+		"js/all.js": {"js/a.js", "js/b.js"},
+	})
+	if err := assets.Refresh(); err != nil {
+		t.Fatalf("Failed to refresh assets: %s", err)
+	}
+
+	url := assets.Url("css/app.css")
+	cssUrl := url
+	if expected := "/public/" + fingerprintName("css/app.css", fmt.Sprintf("%x", md5.Sum([]byte("body {}")))); url != expected {
+		t.Errorf("Expected %s, got %s", expected, url)
+	}
+	if url := assets.Url("css/missing.css"); url != "/public/css/missing.css" {
+		t.Errorf("Expected an unknown asset without a fingerprint, got %s", url)
+	}
+
+	// The fingerprinted asset is served from the original file.
+	file, fingerprinted, err := assets.Open(filepath.Join(dir, filepath.FromSlash(url[len("/public/"):])))
+	if err != nil || !fingerprinted {
+		t.Fatalf("Expected to open the fingerprinted asset: %v", err)
+	}
+	contents, _ := ioutil.ReadAll(file)
+	file.Close()
+	if string(contents) != "body {}" {
+		t.Errorf("Unexpected asset contents %q", contents)
+	}
+
+	// Plain files are left to be served as usual.
+	if _, _, err := assets.Open(filepath.Join(dir, "css", "app.css")); !os.IsNotExist(err) {
+		t.Errorf("Expected a plain file not to be served as an asset, got %v", err)
+	}
+
+	// Bundles are concatenated.
+	url = assets.Url("js/all.js")
+	file, fingerprinted, err = assets.Open(filepath.Join(dir, filepath.FromSlash(url[len("/public/"):])))
+	if err != nil || !fingerprinted {
+		t.Fatalf("Expected to open the fingerprinted bundle %s: %v", url, err)
+	}
+	contents, _ = ioutil.ReadAll(file)
+	if string(contents) != "var a;\nvar b;\n" {
+		t.Errorf("Unexpected bundle contents %q", contents)
+	}
+
+	// Changing an asset changes its fingerprint on refresh.
+	ioutil.WriteFile(filepath.Join(dir, "css", "app.css"), []byte("body { color: red }"), 0644)
+	assets.Refresh()
+	if newUrl := assets.Url("css/app.css"); newUrl == cssUrl {
+		t.Errorf("Expected a new fingerprint, got %s", newUrl)
+	}
+}
@@ -0,0 +1,106 @@
+package revel
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// An in-process store of rendered template fragments (see the "cache" template
+// function), which expire after their time to live.  A key may hold variants
+// of its fragment, e.g. one for each locale.
+//
+// Controllers invalidate fragments, with all of their variants, when the data
+// they show changes:
+//   revel.MainFragmentCache.Delete("hotels.list")
+//   revel.MainFragmentCache.DeletePrefix("hotel." + id + ".")
+type FragmentCache struct {
+	mutex   sync.RWMutex
+	entries map[string]map[string]fragmentCacheEntry // By key, then variant.
+}
+
+type fragmentCacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+// Clean out expired entries when the cache grows beyond this many keys.
+const fragmentCacheSweepSize = 1000
+
+var MainFragmentCache = NewFragmentCache()
+
+func NewFragmentCache() *FragmentCache {
+	return &FragmentCache{entries: map[string]map[string]fragmentCacheEntry{}}
+}
+
+// Return the fragment cached under the key, if it has not expired.
+func (c *FragmentCache) Get(key string) (value string, found bool) {
+	return c.GetVariant(key, "")
+}
+
+// Return the variant of the fragment cached under the key, if it has not
+// expired.
+func (c *FragmentCache) GetVariant(key, variant string) (value string, found bool) {
+	c.mutex.RLock()
+	entry, found := c.entries[key][variant]
+	c.mutex.RUnlock()
+	if !found || time.Now().After(entry.expires) {
+		return "", false
+	}
+	return entry.value, true
+}
+
+// Cache the fragment under the key for the given duration.
+func (c *FragmentCache) Set(key, value string, ttl time.Duration) {
+	c.SetVariant(key, "", value, ttl)
+}
+
+// Cache the variant of the fragment under the key for the given duration.
+func (c *FragmentCache) SetVariant(key, variant, value string, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.entries) >= fragmentCacheSweepSize {
+		now := time.Now()
+		for key, variants := range c.entries {
+			for variant, entry := range variants {
+				if now.After(entry.expires) {
+					delete(variants, variant)
+				}
+			}
+			if len(variants) == 0 {
+				delete(c.entries, key)
+			}
+		}
+	}
+	if c.entries[key] == nil {
+		c.entries[key] = map[string]fragmentCacheEntry{}
+	}
+	c.entries[key][variant] = fragmentCacheEntry{value, time.Now().Add(ttl)}
+}
+
+// Remove the fragments cached under the given keys, in all their variants.
+func (c *FragmentCache) Delete(keys ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
+// Remove the fragments cached under keys beginning with the given prefix.
+func (c *FragmentCache) DeletePrefix(prefix string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Remove all fragments.
+func (c *FragmentCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = map[string]map[string]fragmentCacheEntry{}
+}
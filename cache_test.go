@@ -0,0 +1,43 @@
+package revel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFragmentCache(t *testing.T) {
+	cache := NewFragmentCache()
+	cache.Set("hotel.1.card", "Hilton", time.Minute)
+	cache.Set("hotel.2.card", "Marriott", time.Minute)
+	cache.Set("expired", "Ritz", -time.Second)
+
+	if value, found := cache.Get("hotel.1.card"); !found || value != "Hilton" {
+		t.Errorf("Expected the cached fragment, got %q (%v)", value, found)
+	}
+	if _, found := cache.Get("expired"); found {
+		t.Error("Expected the expired fragment not to be found")
+	}
+
+	cache.DeletePrefix("hotel.1.")
+	if _, found := cache.Get("hotel.1.card"); found {
+		t.Error("Expected the fragment to be deleted by prefix")
+	}
+	if _, found := cache.Get("hotel.2.card"); !found {
+		t.Error("Expected other fragments to be kept")
+	}
+
+	cache.SetVariant("hotels.list", "en", "Hotels", time.Minute)
+	cache.SetVariant("hotels.list", "nl", "Hotels!", time.Minute)
+	if value, found := cache.GetVariant("hotels.list", "nl"); !found || value != "Hotels!" {
+		t.Errorf("Expected the nl variant, got %q (%v)", value, found)
+	}
+	if _, found := cache.Get("hotels.list"); found {
+		t.Error("Expected no fragment without a variant")
+	}
+	cache.Delete("hotels.list")
+	for _, variant := range []string{"en", "nl"} {
+		if _, found := cache.GetVariant("hotels.list", variant); found {
+			t.Errorf("Expected the %s variant to be deleted with the key", variant)
+		}
+	}
+}
@@ -10,7 +10,7 @@ import (
 )
 
 var cmdBuild = &Command{
-	UsageLine: "build [import path] [target path]",
+	UsageLine: "build [-embed] [import path] [target path]",
 	Short:     "build a Revel application (e.g. for deployment)",
 	Long: `
 Build the Revel web application named by the given import path.
@@ -18,9 +18,14 @@ This allows it to be deployed and run on a machine that lacks a Go installation.
 
 WARNING: The target path will be completely deleted, if it already exists!
 
+With -embed, the conf, messages, views and public files of the app (and those
+of Revel and the modules) are embedded in the binary, so that only the binary
+and run scripts are written to the target path.
+
 For example:
 
     revel build github.com/pyanfield/revel/samples/chat /tmp/chat
+    revel build -embed github.com/pyanfield/revel/samples/chat /tmp/chat
 `,
 }
 
@@ -29,6 +34,10 @@ func init() {
 }
 
 func buildApp(args []string) {
+	embed := len(args) > 0 && args[0] == "-embed"
+	if embed {
+		args = args[1:]
+	}
 	if len(args) != 2 {
 		fmt.Fprintf(os.Stderr, "%s\n%s", cmdBuild.UsageLine, cmdBuild.Long)
 		return
@@ -42,26 +51,35 @@ func buildApp(args []string) {
 	os.RemoveAll(destPath)
 	os.MkdirAll(destPath, 0777)
 
-	app, reverr := harness.Build()
+	var app *harness.App
+	var reverr *revel.Error
+	if embed {
+		app, reverr = harness.BuildEmbedded()
+	} else {
+		app, reverr = harness.Build()
+	}
 	panicOnError(reverr, "Failed to build")
 
 	// Included are:
 	// - run scripts
 	// - binary
-	// - revel
-	// - app
-
-	// Revel and the app are in a directory structure mirroring import path
-	srcPath := path.Join(destPath, "src")
-	tmpRevelPath := path.Join(srcPath, filepath.FromSlash(revel.REVEL_IMPORT_PATH))
+	// - revel (unless embedded)
+	// - app (unless embedded)
 	mustCopyFile(path.Join(destPath, filepath.Base(app.BinaryPath)), app.BinaryPath)
-	mustCopyDir(path.Join(tmpRevelPath, "conf"), path.Join(revel.RevelPath, "conf"), nil)
-	mustCopyDir(path.Join(tmpRevelPath, "templates"), path.Join(revel.RevelPath, "templates"), nil)
-	mustCopyDir(path.Join(srcPath, filepath.FromSlash(appImportPath)), revel.BasePath, nil)
+
+	if !embed {
+		// Revel and the app are in a directory structure mirroring import path
+		srcPath := path.Join(destPath, "src")
+		tmpRevelPath := path.Join(srcPath, filepath.FromSlash(revel.REVEL_IMPORT_PATH))
+		mustCopyDir(path.Join(tmpRevelPath, "conf"), path.Join(revel.RevelPath, "conf"), nil)
+		mustCopyDir(path.Join(tmpRevelPath, "templates"), path.Join(revel.RevelPath, "templates"), nil)
+		mustCopyDir(path.Join(srcPath, filepath.FromSlash(appImportPath)), revel.BasePath, nil)
+	}
 
 	tmplData := map[string]interface{}{
 		"BinName":    filepath.Base(app.BinaryPath),
 		"ImportPath": appImportPath,
+		"Embedded":   embed,
 	}
 
 	mustRenderTemplate(
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"fmt"
+	"github.com/pyanfield/revel"
+	"github.com/pyanfield/revel/harness"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var cmdI18n = &Command{
+	UsageLine: "i18n [-stubs] [import path]",
+	Short:     "audit the message keys of a Revel application",
+	Long: `
+Audit the messages of the Revel application named by the given import path.
+
+The message keys used by the app are found in its templates (msg calls) and
+Go source (Message, c.Message and MessageKey calls with literal keys, and the
+validators used), and compared with the messages/ files, to report:
+
+- keys used by the app but missing from a language,
+- keys defined but never used,
+- messages whose placeholders ({name} or %s) differ from the default language,
+- plural forms missing from, or not used by, a language.
+
+Keys of the formatters (format.*, date.*, time.* and currency.*) are optional,
+and are not reported.  The validators fall back to their English messages, so
+their keys (validation.*) are only reported missing from a language if another
+language defines them.
+
+With -stubs, the missing keys are added to the (goconfig) message files of each
+language, with the message of the default language (to be translated), or as
+a commented TODO if the default language lacks it too.
+
+The command exits with a non-zero status if any problems are found, e.g. for
+continuous integration.
+
+For example:
+
+    revel i18n github.com/pyanfield/revel/samples/i18n
+`,
+}
+
+func init() {
+	cmdI18n.Run = auditMessages
+}
+
+// Prefixes of the optional keys, used by Revel itself.
+var optionalMessagePrefixes = []string{"format.", "date.", "time.", "currency."}
+
+var (
+	referencePattern   = regexp.MustCompile(`%\(([^)]+)\)s`)
+	placeholderPattern = regexp.MustCompile(`\{\w+\}|%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z]`)
+	pluralCategories   = []string{revel.PluralZero, revel.PluralOne, revel.PluralTwo,
+		revel.PluralFew, revel.PluralMany, revel.PluralOther}
+)
+
+// The messages of a language.
+type languageMessages struct {
+	files    []string          // The goconfig message files, sorted.
+	messages map[string]string // All keys, in any section, and their messages.
+	refs     map[string]bool   // The keys referenced by other messages, e.g. %(greeting)s
+}
+
+func auditMessages(args []string) {
+	stubs := len(args) > 0 && args[0] == "-stubs"
+	if stubs {
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "%s\n%s", cmdI18n.UsageLine, cmdI18n.Long)
+		return
+	}
+
+	revel.Init("", args[0], "")
+	defaultLanguage := revel.Config.StringDefault("i18n.default_language", "en")
+	languages := loadLanguageMessages(filepath.Join(revel.BasePath, "messages"))
+	if len(languages) == 0 {
+		errorf("No message files found in %s", filepath.Join(revel.BasePath, "messages"))
+	}
+
+	// Find the keys used by the app.
+	uses, compileError := harness.FindMessageKeys(revel.CodePaths)
+	panicOnError(compileError, "Failed to parse the app source")
+	uses = append(uses, harness.FindTemplateMessageKeys(revel.TemplatePaths)...)
+	usedKeys := map[string]*harness.MessageKeyUse{}
+	for _, use := range uses {
+		if _, found := usedKeys[use.Key]; !found {
+			usedKeys[use.Key] = use
+		}
+	}
+
+	var names []string
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	problems := 0
+	report := func(format string, args ...interface{}) {
+		fmt.Printf("  "+format+"\n", args...)
+		problems++
+	}
+
+	fmt.Println("Missing messages:")
+	missing := map[string][]string{}
+	for _, name := range names {
+		for _, key := range sortedUseKeys(usedKeys) {
+			if !isOptionalMessage(key) && !hasMessage(languages[name], key) &&
+				(!isValidationMessage(key) || isTranslated(languages, key)) {
+				use := usedKeys[key]
+				report("%s: %s (%s:%d)", name, key, relativePath(use.Path), use.Line)
+				missing[name] = append(missing[name], key)
+			}
+		}
+	}
+
+	fmt.Println("Unused messages:")
+	for _, name := range names {
+		for _, key := range sortedKeys(languages[name].messages) {
+			if !isOptionalMessage(key) && !languages[name].refs[key] && !isUsedMessage(usedKeys, key) {
+				report("%s: %s", name, key)
+			}
+		}
+	}
+
+	fmt.Println("Mismatched placeholders:")
+	if defaultMessages, found := languages[defaultLanguage]; found {
+		for _, name := range names {
+			if name == defaultLanguage {
+				continue
+			}
+			for _, key := range sortedKeys(languages[name].messages) {
+				expected, found := defaultMessages.messages[key]
+				if !found {
+					continue
+				}
+				if want, got := placeholders(expected), placeholders(languages[name].messages[key]); want != got {
+					report("%s: %s has %s, but %s has %s", name, key, got, defaultLanguage, want)
+				}
+			}
+		}
+	}
+
+	fmt.Println("Plural forms:")
+	for _, name := range names {
+		categories := languagePluralCategories(name)
+		for _, base := range pluralMessageKeys(languages[name].messages) {
+			var lacking, unused []string
+			for _, category := range pluralCategories {
+				_, defined := languages[name].messages[base+"."+category]
+				switch {
+				case categories[category] && !defined && category != revel.PluralZero:
+					lacking = append(lacking, category)
+				case !categories[category] && defined && category != revel.PluralZero:
+					unused = append(unused, category)
+				}
+			}
+			if len(lacking) > 0 {
+				report("%s: %s lacks the forms %s", name, base, strings.Join(lacking, ", "))
+			}
+			if len(unused) > 0 {
+				report("%s: %s has the forms %s, which the language does not use", name, base, strings.Join(unused, ", "))
+			}
+		}
+	}
+
+	if stubs {
+		for _, name := range names {
+			if len(missing[name]) > 0 && len(languages[name].files) == 0 {
+				fmt.Printf("No goconfig message file to add the missing %s messages to\n", name)
+			} else if len(missing[name]) > 0 {
+				writeMessageStubs(languages[name].files[0], missing[name], languages[defaultLanguage])
+			}
+		}
+	}
+
+	if problems > 0 {
+		errorf("%d problems found", problems)
+	}
+	fmt.Println("No problems found")
+}
+
+// Read the message files in the given directory, by language.
+func loadLanguageMessages(dir string) map[string]*languageMessages {
+	languages := map[string]*languageMessages{}
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		name, messageConfig, err := revel.ParseMessageFile(path)
+		if err != nil {
+			errorf("Failed to read %s: %s", path, err)
+		}
+		if messageConfig == nil {
+			return nil
+		}
+
+		language, found := languages[name]
+		if !found {
+			language = &languageMessages{messages: map[string]string{}, refs: map[string]bool{}}
+			languages[name] = language
+		}
+		// (The goconfig files are named by language, e.g. hotels.fr, unlike
+		// hotels.fr.po)
+		if strings.ToLower(filepath.Ext(path)) == "."+name {
+			language.files = append(language.files, path)
+		}
+		for _, section := range messageConfig.Sections() {
+			options, _ := messageConfig.Options(section)
+			for _, option := range options {
+				if raw, err := messageConfig.RawString(section, option); err == nil {
+					for _, match := range referencePattern.FindAllStringSubmatch(raw, -1) {
+						language.refs[match[1]] = true
+					}
+				}
+				if _, found := language.messages[option]; found {
+					continue
+				}
+				value, _ := messageConfig.String(section, option)
+				language.messages[option] = value
+			}
+		}
+		return nil
+	})
+	for _, language := range languages {
+		sort.Strings(language.files)
+	}
+	return languages
+}
+
+// The validators fall back to their English messages, so their keys are only
+// missing from a language if another language translates them.
+func isValidationMessage(key string) bool {
+	return strings.HasPrefix(key, "validation.")
+}
+
+// Return true if any language defines the key.
+func isTranslated(languages map[string]*languageMessages, key string) bool {
+	for _, language := range languages {
+		if hasMessage(language, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func isOptionalMessage(key string) bool {
+	for _, prefix := range optionalMessagePrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return true if the language defines the key, or any of its plural forms.
+func hasMessage(language *languageMessages, key string) bool {
+	if _, found := language.messages[key]; found {
+		return true
+	}
+	for _, category := range pluralCategories {
+		if _, found := language.messages[key+"."+category]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// Return true if the key, or the message of which it is a plural form, is used.
+func isUsedMessage(usedKeys map[string]*harness.MessageKeyUse, key string) bool {
+	if _, found := usedKeys[key]; found {
+		return true
+	}
+	if dot := strings.LastIndex(key, "."); dot != -1 && isPluralCategory(key[dot+1:]) {
+		_, found := usedKeys[key[:dot]]
+		return found
+	}
+	return false
+}
+
+func isPluralCategory(name string) bool {
+	for _, category := range pluralCategories {
+		if name == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the messages that have plural forms, e.g. "hotels.count" for
+// "hotels.count.one" and "hotels.count.other".
+func pluralMessageKeys(messages map[string]string) []string {
+	bases := map[string]string{}
+	for key := range messages {
+		if dot := strings.LastIndex(key, "."); dot != -1 && isPluralCategory(key[dot+1:]) {
+			bases[key[:dot]] = key
+		}
+	}
+	return sortedKeys(bases)
+}
+
+// Return the plural categories the language uses, by trying some numbers.
+func languagePluralCategories(language string) map[string]bool {
+	categories := map[string]bool{revel.PluralOther: true}
+	for n := 0; n <= 200; n++ {
+		categories[revel.PluralCategory(language, n)] = true
+	}
+	categories[revel.PluralCategory(language, 1.5)] = true
+	return categories
+}
+
+// Describe the placeholders of the message, sorted, e.g. "{count}, %s".
+func placeholders(message string) string {
+	found := placeholderPattern.FindAllString(strings.Replace(message, "%%", "", -1), -1)
+	sort.Strings(found)
+	if len(found) == 0 {
+		return "no placeholders"
+	}
+	return strings.Join(found, ", ")
+}
+
+// Add the keys to the message file, before its first section, with the
+// messages of the default language, or as commented TODOs where the default
+// language lacks them.
+func writeMessageStubs(fileName string, keys []string, defaultMessages *languageMessages) {
+	fileBytes, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		errorf("Failed to read %s: %s", fileName, err)
+	}
+	lines := strings.Split(string(fileBytes), "\n")
+	insertAt := len(lines)
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "[") {
+			insertAt = i
+			break
+		}
+	}
+
+	stubs := []string{"# Added by revel i18n, to be translated:"}
+	for _, key := range keys {
+		if defaultMessages != nil {
+			if defaultMessage, found := defaultMessages.messages[key]; found {
+				stubs = append(stubs, key+"="+defaultMessage)
+				continue
+			}
+		}
+		stubs = append(stubs, "# TODO: "+key+"=")
+	}
+	stubs = append(stubs, "")
+
+	lines = append(lines[:insertAt], append(stubs, lines[insertAt:]...)...)
+	if err = ioutil.WriteFile(fileName, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		errorf("Failed to write %s: %s", fileName, err)
+	}
+	fmt.Printf("Added %d messages to %s\n", len(keys), relativePath(fileName))
+}
+
+func sortedKeys(m map[string]string) (keys []string) {
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedUseKeys(m map[string]*harness.MessageKeyUse) (keys []string) {
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func relativePath(path string) string {
+	if rel, err := filepath.Rel(revel.BasePath, path); err == nil {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"github.com/pyanfield/revel/harness"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlaceholders(t *testing.T) {
+	var placeholderTests = []struct {
+		message, expected string
+	}{
+		{"Hello", "no placeholders"},
+		{"100%% sure", "no placeholders"},
+		{"%d hotels in %s", "%d, %s"},
+		{"%s has %.2f stars", "%.2f, %s"},
+		{"{count} hotels in {city}", "{city}, {count}"},
+		{"{city}: %d", "%d, {city}"},
+	}
+	for _, test := range placeholderTests {
+		if actual := placeholders(test.message); actual != test.expected {
+			t.Errorf("%q: expected %s, got %s", test.message, test.expected, actual)
+		}
+	}
+
+	// The order of the placeholders may differ between languages.
+	if placeholders("{count} hotels in {city}") != placeholders("In {city}: {count} hotels") {
+		t.Error("Expected the placeholders to match in any order")
+	}
+}
+
+func TestPluralMessageKeys(t *testing.T) {
+	messages := map[string]string{
+		"hotels.count.one":   "%d hotel",
+		"hotels.count.other": "%d hotels",
+		"stars.few":          "%d stars",
+		"title":              "Hotels",
+		"menu.other":         "Other",
+		"menu.otherwise":     "Otherwise",
+	}
+	expected := []string{"hotels.count", "menu", "stars"}
+	if actual := pluralMessageKeys(messages); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+}
+
+func TestLanguagePluralCategories(t *testing.T) {
+	var categoryTests = []struct {
+		language string
+		expected map[string]bool
+	}{
+		{"en", map[string]bool{"one": true, "other": true}},
+		{"ja", map[string]bool{"other": true}},
+		{"ru", map[string]bool{"one": true, "few": true, "many": true, "other": true}},
+	}
+	for _, test := range categoryTests {
+		if actual := languagePluralCategories(test.language); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.language, test.expected, actual)
+		}
+	}
+}
+
+func TestMessageUses(t *testing.T) {
+	language := &languageMessages{messages: map[string]string{
+		"title":              "Hotels",
+		"hotels.count.one":   "%d hotel",
+		"hotels.count.other": "%d hotels",
+	}}
+	for key, expected := range map[string]bool{"title": true, "hotels.count": true, "hotels": false} {
+		if hasMessage(language, key) != expected {
+			t.Errorf("%s: expected hasMessage %v", key, expected)
+		}
+	}
+
+	usedKeys := map[string]*harness.MessageKeyUse{"hotels.count": {Key: "hotels.count"}}
+	for key, expected := range map[string]bool{"hotels.count": true, "hotels.count.one": true, "title": false, "hotels.count.label": false} {
+		if isUsedMessage(usedKeys, key) != expected {
+			t.Errorf("%s: expected isUsedMessage %v", key, expected)
+		}
+	}
+}
+
+func TestLoadLanguageMessages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-messages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "hotels.en"), []byte("greeting=Hello\nwelcome=%(greeting)s, %s\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "hotels.nl.json"), []byte(`{"greeting": "Hallo"}`), 0644)
+
+	languages := loadLanguageMessages(dir)
+	if len(languages) != 2 {
+		t.Fatalf("Expected 2 languages, got %v", languages)
+	}
+	en, nl := languages["en"], languages["nl"]
+	if en.messages["welcome"] != "Hello, %s" || !en.refs["greeting"] {
+		t.Errorf("Unexpected en messages: %v, refs %v", en.messages, en.refs)
+	}
+	if len(en.files) != 1 || !strings.HasSuffix(en.files[0], "hotels.en") {
+		t.Errorf("Expected hotels.en to be the goconfig file of en, got %v", en.files)
+	}
+	if nl.messages["greeting"] != "Hallo" || len(nl.files) != 0 {
+		t.Errorf("Unexpected nl messages: %v, files %v", nl.messages, nl.files)
+	}
+}
+
+func TestWriteMessageStubs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-messages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "hotels.nl")
+	ioutil.WriteFile(fileName, []byte("greeting=Hallo\n\n[nl-BE]\ngreeting=Dag\n"), 0644)
+
+	defaultMessages := &languageMessages{messages: map[string]string{"title": "Hotels"}}
+	writeMessageStubs(fileName, []string{"title", "validation.required"}, defaultMessages)
+
+	fileBytes, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "greeting=Hallo\n\n# Added by revel i18n, to be translated:\ntitle=Hotels\n" +
+		"# TODO: validation.required=\n\n[nl-BE]\ngreeting=Dag\n"
+	if string(fileBytes) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, fileBytes)
+	}
+}
+
+func TestMissingValidationMessages(t *testing.T) {
+	languages := map[string]*languageMessages{
+		"en": {messages: map[string]string{"title": "Hotels"}},
+		"fr": {messages: map[string]string{"validation.required": "Obligatoire"}},
+	}
+	if !isTranslated(languages, "validation.required") || isTranslated(languages, "validation.min") {
+		t.Error("Expected only validation.required to be translated")
+	}
+	if !isValidationMessage("validation.min") || isValidationMessage("title") {
+		t.Error("Expected only validation.min to be a validation message")
+	}
+}
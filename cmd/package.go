@@ -9,11 +9,12 @@ import (
 )
 
 var cmdPackage = &Command{
-	UsageLine: "package [import path]",
+	UsageLine: "package [-embed] [import path]",
 	Short:     "package a Revel application (e.g. for deployment)",
 	Long: `
 Package the Revel web application named by the given import path.
 This allows it to be deployed and run on a machine that lacks a Go installation.
+With -embed, the app's files are embedded in the binary (see "revel help build").
 
 For example:
 
@@ -31,6 +32,16 @@ func packageApp(args []string) {
 		return
 	}
 
+	var buildArgs []string
+	if args[0] == "-embed" {
+		buildArgs = append(buildArgs, args[0])
+		args = args[1:]
+		if len(args) == 0 {
+			fmt.Fprint(os.Stderr, cmdPackage.Long)
+			return
+		}
+	}
+
 	appImportPath := args[0]
 	revel.Init("", appImportPath, "")
 
@@ -42,7 +53,7 @@ func packageApp(args []string) {
 	tmpDir, err := ioutil.TempDir("", path.Base(revel.BasePath))
 	panicOnError(err, "Failed to get temp dir")
 
-	buildApp([]string{args[0], tmpDir})
+	buildApp(append(buildArgs, args[0], tmpDir))
 
 	// Create the zip file.
 	archiveName := mustTarGzDir(destFile, tmpDir)
@@ -32,6 +32,7 @@ var commands = []*Command{
 	cmdPackage,
 	cmdClean,
 	cmdTest,
+	cmdI18n,
 }
 
 func main() {
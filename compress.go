@@ -0,0 +1,279 @@
+package revel
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// This plugin compresses responses with gzip or deflate, as negotiated with
+// the client's Accept-Encoding header.  It is configured in app.conf:
+//
+//   results.compressed = true
+//   results.compressed.level = 6       (1-9, or -1 for the default)
+//   results.compressed.minsize = 1024  (smaller responses are sent as they are)
+//   results.compressed.types = html, css, js, json, xml, application/xml, txt, svg
+//
+// The types are file extensions, as listed in conf/mime-types.conf, or MIME
+// types.  Responses of other types, which are usually compressed already (e.g.
+// images and archives), are sent as they are.
+type CompressionPlugin struct {
+	EmptyPlugin
+}
+
+var (
+	compressionEnabled bool
+	compressionLevel   = gzip.DefaultCompression
+	compressionMinSize = 1024
+	compressionTypes   []string
+)
+
+const defaultCompressionTypes = "html, css, js, json, xml, application/xml, txt, svg"
+
+func (p CompressionPlugin) OnAppStart() {
+	compressionEnabled = Config.BoolDefault("results.compressed", false)
+	compressionLevel = Config.IntDefault("results.compressed.level", gzip.DefaultCompression)
+	compressionMinSize = Config.IntDefault("results.compressed.minsize", 1024)
+	compressionTypes = compressionContentTypes(Config.StringDefault("results.compressed.types", defaultCompressionTypes))
+}
+
+// Return the MIME types for the given list of extensions and MIME types.
+func compressionContentTypes(list string) (types []string) {
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !strings.Contains(name, "/") && mimeConfig != nil {
+			contentType := mimeConfig.StringDefault(strings.TrimPrefix(name, "."), "")
+			if contentType == "" {
+				WARN.Println("results.compressed.types: unknown extension", name)
+				continue
+			}
+			name = contentType
+		}
+		types = append(types, name)
+	}
+	return
+}
+
+func (p CompressionPlugin) BeforeRequest(c *Controller) {
+	if !compressionEnabled {
+		return
+	}
+	// Websockets and HEAD requests have no body to compress.
+	if c.Request.Method == "WS" || c.Request.Method == "HEAD" ||
+		c.Request.Header.Get("Upgrade") != "" {
+		return
+	}
+	encoding := negotiateEncoding(c.Request.Header.Get("Accept-Encoding"))
+	if encoding == "" {
+		return
+	}
+	c.Response.Out = &CompressResponseWriter{
+		ResponseWriter: c.Response.Out,
+		encoding:       encoding,
+		level:          compressionLevel,
+		minSize:        compressionMinSize,
+		types:          compressionTypes,
+	}
+}
+
+func (p CompressionPlugin) Finally(c *Controller) {
+	if cw, ok := c.Response.Out.(*CompressResponseWriter); ok {
+		if err := cw.Close(); err != nil {
+			TRACE.Println("Failed to finish compressed response:", err)
+		}
+	}
+}
+
+// Return the encoding to use for the given Accept-Encoding header: "gzip",
+// "deflate" or "" for none.  gzip is preferred if the client accepts both
+// equally.
+func negotiateEncoding(header string) string {
+	var best string
+	var bestQ float64
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = value
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		switch coding {
+		case "*":
+			coding = "gzip"
+		case "gzip", "deflate":
+		default:
+			continue
+		}
+		if q > bestQ || (q == bestQ && coding == "gzip") {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// This ResponseWriter compresses what is written to it, if the response is of
+// a compressible type and not too small.  Up to the minimum size, the body is
+// buffered before deciding; a Flush (e.g. by a streaming Result) decides
+// straight away.
+type CompressResponseWriter struct {
+	http.ResponseWriter
+	encoding string
+	level    int
+	minSize  int
+	types    []string
+
+	status     int
+	decided    bool
+	buffer     bytes.Buffer
+	compressor io.WriteCloser
+	closed     bool
+}
+
+func (w *CompressResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	// Responses without bodies are sent straight away.
+	if status == http.StatusNoContent || status == http.StatusNotModified || status < 200 {
+		w.decide(false)
+	}
+}
+
+func (w *CompressResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.decided {
+		w.buffer.Write(p)
+		if w.buffer.Len() >= w.minSize {
+			if err := w.decide(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+	if w.compressor != nil {
+		return w.compressor.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Send what has been written so far to the client.
+func (w *CompressResponseWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.decide(true)
+	}
+	if flusher, ok := w.compressor.(interface {
+		Flush() error
+	}); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *CompressResponseWriter) CloseNotify() <-chan bool {
+	if closeNotifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return closeNotifier.CloseNotify()
+	}
+	return nil
+}
+
+// Finish the response, sending whatever is buffered.
+func (w *CompressResponseWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if !w.decided {
+		if w.status == 0 {
+			// Nothing was written.
+			return nil
+		}
+		if err := w.decide(w.buffer.Len() >= w.minSize); err != nil {
+			return err
+		}
+	}
+	if w.compressor != nil {
+		return w.compressor.Close()
+	}
+	return nil
+}
+
+// Decide whether to compress the response, given whether it is large enough,
+// then send the header and what has been buffered.
+func (w *CompressResponseWriter) decide(largeEnough bool) error {
+	w.decided = true
+	header := w.Header()
+	compressible := w.compressible()
+	if compressible {
+		header.Add("Vary", "Accept-Encoding")
+	}
+
+	if compressible && largeEnough && w.status != http.StatusPartialContent &&
+		header.Get("Content-Encoding") == "" && header.Get("Content-Range") == "" {
+		var err error
+		if w.encoding == "gzip" {
+			w.compressor, err = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+		} else {
+			w.compressor, err = flate.NewWriter(w.ResponseWriter, w.level)
+		}
+		if err != nil {
+			ERROR.Println("Failed to create compressor:", err)
+			w.compressor = nil
+		} else {
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+		}
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.buffer.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.compressor != nil {
+		_, err = w.compressor.Write(w.buffer.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buffer.Bytes())
+	}
+	w.buffer.Reset()
+	return err
+}
+
+// Return true if the Content-Type of the response is one to compress.
+func (w *CompressResponseWriter) compressible() bool {
+	contentType := w.Header().Get("Content-Type")
+	if i := strings.Index(contentType, ";"); i != -1 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.TrimSpace(strings.ToLower(contentType))
+	for _, t := range w.types {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
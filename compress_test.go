@@ -0,0 +1,144 @@
+package revel
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	var encodingTests = []struct {
+		header, expected string
+	}{
+		{"", ""},
+		{"gzip, deflate", "gzip"},
+		{"deflate", "deflate"},
+		{"deflate, gzip;q=0.5", "deflate"},
+		{"gzip;q=0, deflate", "deflate"},
+		{"*", "gzip"},
+		{"identity", ""},
+		{"br, sdch", ""},
+	}
+	for _, test := range encodingTests {
+		if actual := negotiateEncoding(test.header); actual != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.header, test.expected, actual)
+		}
+	}
+}
+
+func newCompressTestWriter(encoding string) (*CompressResponseWriter, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	return &CompressResponseWriter{
+		ResponseWriter: recorder,
+		encoding:       encoding,
+		level:          gzip.DefaultCompression,
+		minSize:        100,
+		types:          []string{"text/html", "application/json"},
+	}, recorder
+}
+
+func TestCompressResponseWriter(t *testing.T) {
+	body := strings.Repeat("<p>Hello, world</p>", 20)
+
+	// Large enough bodies of compressible types are compressed.
+	w, recorder := newCompressTestWriter("gzip")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(body))
+	w.Close()
+	if encoding := recorder.HeaderMap.Get("Content-Encoding"); encoding != "gzip" {
+		t.Fatalf("Expected gzip encoding, got %q", encoding)
+	}
+	if vary := recorder.HeaderMap.Get("Vary"); vary != "Accept-Encoding" {
+		t.Errorf("Expected Vary: Accept-Encoding, got %q", vary)
+	}
+	reader, err := gzip.NewReader(recorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decompressed, _ := ioutil.ReadAll(reader); string(decompressed) != body {
+		t.Errorf("Unexpected decompressed body: %s", decompressed)
+	}
+
+	// deflate
+	w, recorder = newCompressTestWriter("deflate")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(body))
+	w.Close()
+	if encoding := recorder.HeaderMap.Get("Content-Encoding"); encoding != "deflate" {
+		t.Fatalf("Expected deflate encoding, got %q", encoding)
+	}
+	if decompressed, _ := ioutil.ReadAll(flate.NewReader(recorder.Body)); string(decompressed) != body {
+		t.Errorf("Unexpected decompressed body: %s", decompressed)
+	}
+}
+
+func TestCompressResponseWriterSkips(t *testing.T) {
+	body := strings.Repeat("x", 200)
+	var skipTests = []struct {
+		description, contentType, contentEncoding, body string
+		vary                                             bool
+	}{
+		{"small bodies", "text/html", "", "small", true},
+		{"other types", "image/png", "", body, false},
+		{"encoded bodies", "text/html", "gzip", body, true},
+	}
+	for _, test := range skipTests {
+		w, recorder := newCompressTestWriter("gzip")
+		w.Header().Set("Content-Type", test.contentType)
+		if test.contentEncoding != "" {
+			w.Header().Set("Content-Encoding", test.contentEncoding)
+		}
+		w.Write([]byte(test.body))
+		w.Close()
+		if encoding := recorder.HeaderMap.Get("Content-Encoding"); encoding != test.contentEncoding {
+			t.Errorf("%s: expected encoding %q, got %q", test.description, test.contentEncoding, encoding)
+		}
+		if actual := recorder.Body.String(); actual != test.body {
+			t.Errorf("%s: expected the body as it was, got %q", test.description, actual)
+		}
+		if vary := recorder.HeaderMap.Get("Vary") != ""; vary != test.vary {
+			t.Errorf("%s: expected Vary %v", test.description, test.vary)
+		}
+	}
+}
+
+func TestCompressedStream(t *testing.T) {
+	req, recorder := newStreamTestRequest()
+	resp := NewResponse(&CompressResponseWriter{
+		ResponseWriter: recorder,
+		encoding:       "gzip",
+		level:          gzip.DefaultCompression,
+		minSize:        1024,
+		types:          []string{"text/plain"},
+	})
+	var flushed bool
+	result := &StreamResult{
+		ContentType: "text/plain",
+		Stream: func(w *StreamWriter) error {
+			w.Write([]byte("first"))
+			w.Flush()
+			// The flush sends what was written, though small, compressed.
+			flushed = recorder.Flushed && recorder.Body.Len() > 0
+			w.Write([]byte("second"))
+			return nil
+		},
+	}
+	result.Apply(req, resp)
+	resp.Out.(*CompressResponseWriter).Close()
+
+	if !flushed {
+		t.Error("Expected the stream to be flushed through the compressor")
+	}
+	reader, err := gzip.NewReader(recorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decompressed, _ := ioutil.ReadAll(reader); string(decompressed) != "firstsecond" {
+		t.Errorf("Unexpected decompressed body: %s", decompressed)
+	}
+}
@@ -0,0 +1,142 @@
+package revel
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// This result answers a conditional GET with 304 (Not Modified), telling the
+// client that the copy it has is current.  (See Controller.NotModifiedIf)
+type NotModifiedResult struct{}
+
+func (r NotModifiedResult) Apply(req *Request, resp *Response) {
+	// A 304 has no body, so it must not describe one.
+	resp.Out.Header().Del("Content-Type")
+	resp.Out.Header().Del("Content-Length")
+	resp.Out.WriteHeader(http.StatusNotModified)
+}
+
+// Return true if the request's If-None-Match header matches the given ETag.
+// ETags are compared weakly, i.e. W/"x" matches "x".
+func etagMatches(req *Request, etag string) bool {
+	ifNoneMatch := req.Header.Get("If-None-Match")
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Return true if the request's If-Modified-Since header is no earlier than the
+// given modification time.  (Which is only consulted without If-None-Match)
+func notModifiedSince(req *Request, lastModified time.Time) bool {
+	if lastModified.IsZero() || req.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// HTTP dates have a resolution of a second.
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// Quote the ETag, unless it is already quoted.
+func quoteEtag(etag string) string {
+	if etag == "" || strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// This plugin gives responses to GET requests weak ETags, computed over the
+// rendered body, and answers requests whose If-None-Match header matches with
+// 304 (Not Modified).  It applies to templates, JSON and XML, and is turned
+// on in app.conf:
+//
+//   results.etag = true
+//
+// The body is still rendered, but is not sent to clients that have it.
+type ETagPlugin struct {
+	EmptyPlugin
+}
+
+func (p ETagPlugin) AfterRequest(c *Controller) {
+	if Config == nil || !Config.BoolDefault("results.etag", false) {
+		return
+	}
+	if c.Request.Method != "GET" && c.Request.Method != "HEAD" {
+		return
+	}
+	switch result := c.Result.(type) {
+	case *RenderTemplateResult:
+		if !result.Stream {
+			c.Result = &etagResult{result}
+		}
+	case RenderJsonResult, RenderXmlResult:
+		c.Result = &etagResult{result}
+	}
+}
+
+// This result renders the result it wraps into a buffer, to compute a weak
+// ETag over the body.
+type etagResult struct {
+	Result
+}
+
+func (r *etagResult) Apply(req *Request, resp *Response) {
+	buffer := &bufferedResponseWriter{header: resp.Out.Header()}
+	r.Result.Apply(req, &Response{Status: resp.Status, ContentType: resp.ContentType, Out: buffer})
+	if buffer.status == 0 {
+		buffer.status = http.StatusOK
+	}
+
+	// Only successful responses are tagged, and the action may have set its
+	// own ETag.
+	if buffer.status == http.StatusOK {
+		etag := resp.Out.Header().Get("ETag")
+		if etag == "" {
+			etag = fmt.Sprintf(`W/"%x"`, md5.Sum(buffer.body.Bytes()))
+			resp.Out.Header().Set("ETag", etag)
+		}
+		if etagMatches(req, etag) {
+			NotModifiedResult{}.Apply(req, resp)
+			return
+		}
+	}
+
+	resp.Status = buffer.status
+	resp.Out.WriteHeader(buffer.status)
+	resp.Out.Write(buffer.body.Bytes())
+}
+
+// A ResponseWriter that holds the status and body, sharing the header of the
+// real one.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedResponseWriter) Write(p []byte) (int, error) {
+	return w.body.Write(p)
+}
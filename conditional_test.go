@@ -0,0 +1,117 @@
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newConditionalTestController(header map[string]string) (*Controller, *httptest.ResponseRecorder) {
+	httpReq, _ := http.NewRequest("GET", "/hotels/1", nil)
+	for key, value := range header {
+		httpReq.Header.Set(key, value)
+	}
+	recorder := httptest.NewRecorder()
+	return &Controller{Request: NewRequest(httpReq), Response: NewResponse(recorder)}, recorder
+}
+
+func TestNotModifiedIf(t *testing.T) {
+	updated := time.Date(2013, 6, 1, 12, 0, 0, 0, time.UTC)
+	var conditionalTests = []struct {
+		description string
+		header      map[string]string
+		notModified bool
+	}{
+		{"no conditions", nil, false},
+		{"matching ETag", map[string]string{"If-None-Match": `"v2"`}, true},
+		{"matching weak ETag", map[string]string{"If-None-Match": `"v1", W/"v2"`}, true},
+		{"stale ETag", map[string]string{"If-None-Match": `"v1"`}, false},
+		{"unmodified", map[string]string{"If-Modified-Since": updated.Format(http.TimeFormat)}, true},
+		{"modified", map[string]string{"If-Modified-Since": updated.Add(-time.Hour).Format(http.TimeFormat)}, false},
+		// If-None-Match takes precedence over If-Modified-Since.
+		{"stale ETag, unmodified", map[string]string{
+			"If-None-Match":     `"v1"`,
+			"If-Modified-Since": updated.Format(http.TimeFormat),
+		}, false},
+	}
+	for _, test := range conditionalTests {
+		c, recorder := newConditionalTestController(test.header)
+		result := c.NotModifiedIf("v2", updated)
+		if (result != nil) != test.notModified {
+			t.Errorf("%s: expected not modified to be %v", test.description, test.notModified)
+			continue
+		}
+		if etag := recorder.HeaderMap.Get("ETag"); etag != `"v2"` {
+			t.Errorf("%s: expected ETag \"v2\", got %s", test.description, etag)
+		}
+		if lastModified := recorder.HeaderMap.Get("Last-Modified"); lastModified != updated.Format(http.TimeFormat) {
+			t.Errorf("%s: unexpected Last-Modified %s", test.description, lastModified)
+		}
+		if result != nil {
+			result.Apply(c.Request, c.Response)
+			if recorder.Code != http.StatusNotModified {
+				t.Errorf("%s: expected 304, got %d", test.description, recorder.Code)
+			}
+		}
+	}
+}
+
+type etagTestHotel struct {
+	Name string
+}
+
+func TestEtagResult(t *testing.T) {
+	loadTestI18nConfig(t)
+	results := []Result{
+		RenderJsonResult{map[string]string{"name": "Marriott"}},
+		RenderXmlResult{etagTestHotel{"Marriott"}},
+	}
+	for _, result := range results {
+		c, recorder := newConditionalTestController(nil)
+		(&etagResult{result}).Apply(c.Request, c.Response)
+		etag := recorder.HeaderMap.Get("ETag")
+		if recorder.Code != http.StatusOK || recorder.Body.Len() == 0 {
+			t.Fatalf("%T: expected the body, got %d %q", result, recorder.Code, recorder.Body.String())
+		}
+		if len(etag) < 4 || etag[:3] != `W/"` {
+			t.Fatalf("%T: expected a weak ETag, got %q", result, etag)
+		}
+
+		// The same body has the same ETag, and is not sent again.
+		c, recorder = newConditionalTestController(map[string]string{"If-None-Match": etag})
+		(&etagResult{result}).Apply(c.Request, c.Response)
+		if recorder.Code != http.StatusNotModified || recorder.Body.Len() != 0 {
+			t.Errorf("%T: expected an empty 304, got %d %q", result, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+func TestEtagResultTemplate(t *testing.T) {
+	loadTestI18nConfig(t)
+	loader := NewTemplateLoader([]string{testViewsPath})
+	if err := loader.Refresh(); err != nil {
+		t.Fatalf("Failed to load templates: %s", err)
+	}
+	tmpl, err := loader.Template("Hotels/Show.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &etagResult{&RenderTemplateResult{
+		Template:   tmpl,
+		RenderArgs: map[string]interface{}{"name": "Marriott"},
+	}}
+
+	c, recorder := newConditionalTestController(nil)
+	result.Apply(c.Request, c.Response)
+	etag := recorder.HeaderMap.Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag")
+	}
+
+	c, recorder = newConditionalTestController(map[string]string{"If-None-Match": etag})
+	result.Apply(c.Request, c.Response)
+	if recorder.Code != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", recorder.Code)
+	}
+}
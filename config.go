@@ -3,6 +3,8 @@ package revel
 import (
 	"errors"
 	"github.com/robfig/config"
+	"io/ioutil"
+	"os"
 	"path"
 	"strings"
 )
@@ -19,7 +21,7 @@ type MergedConfig struct {
 func LoadConfig(confName string) (*MergedConfig, error) {
 	var err error
 	for _, confPath := range ConfPaths {
-		conf, err := config.ReadDefault(path.Join(confPath, confName))
+		conf, err := readConfigFile(path.Join(confPath, confName))
 		if err == nil {
 			return &MergedConfig{conf, ""}, nil
 		}
@@ -30,6 +32,31 @@ func LoadConfig(confName string) (*MergedConfig, error) {
 	return nil, err
 }
 
+// Read the config file at the given path, which may be embedded in the binary.
+// The config package reads only from the filesystem, so embedded files are
+// first copied to a temporary file.
+func readConfigFile(fileName string) (*config.Config, error) {
+	if _, ok := embeddedKey(fileName); !ok {
+		return config.ReadDefault(fileName)
+	}
+
+	contents, err := ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	tmpFile, err := ioutil.TempFile("", "revel-config")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(contents)
+	tmpFile.Close()
+	if err != nil {
+		return nil, err
+	}
+	return config.ReadDefault(tmpFile.Name())
+}
+
 func (c *MergedConfig) SetSection(section string) {
 	c.section = section
 }
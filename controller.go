@@ -100,6 +100,11 @@ func (c *Controller) Invoke(appControllerPtr reflect.Value, method reflect.Value
 	// Run the plugins.
 	plugins.BeforeRequest(c)
 
+	// API requests may be rejected for failing their arguments' validation.
+	if c.Result == nil {
+		c.Result = validateBoundArgs(c, methodArgs)
+	}
+
 	if c.Result == nil {
 		// Invoke the action.
 		var resultValue reflect.Value
@@ -217,12 +222,68 @@ func (c *Controller) RenderTemplate(templatePath string) Result {
 	}
 }
 
+// Renders the given template like RenderTemplate, but streams it to the client
+// as it renders, flushing every few KB, for very large pages.
+// Errors in the template can not be shown on an error page, as the status has
+// been sent by then.
+func (c *Controller) RenderTemplateStream(templatePath string) Result {
+	template, err := MainTemplateLoader.Template(templatePath)
+	if err != nil {
+		return c.RenderError(err)
+	}
+
+	return &RenderTemplateResult{
+		Template:   template,
+		RenderArgs: c.RenderArgs,
+		Stream:     true,
+	}
+}
+
+// Streams the response written by the given func to the client, e.g. a large
+// export.  (See StreamResult)
+func (c *Controller) RenderStream(contentType string, stream func(w *StreamWriter) error) Result {
+	return &StreamResult{
+		ContentType: contentType,
+		Stream:      stream,
+	}
+}
+
+// Sends the events from the channel to the client as Server-Sent Events, until
+// the channel is closed or the client disconnects.  onClose (which may be nil)
+// is called when the stream ends, e.g. to unsubscribe.  (See EventStreamResult)
+func (c *Controller) RenderEventStream(events <-chan ServerEvent, onClose func()) Result {
+	return &EventStreamResult{
+		Events:  events,
+		OnClose: onClose,
+	}
+}
+
+// Return the id of the last event received by a reconnecting Server-Sent
+// Events client, from which the stream should resume.  Clients that can not
+// set the Last-Event-ID header may send the lastEventId parameter.
+func (c *Controller) LastEventId() string {
+	if id := c.Request.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return c.Params.Get("lastEventId")
+}
+
 // Uses encoding/json.Marshal to return JSON to the client.
 // Will serialie it using json.Marshal
 func (c *Controller) RenderJson(o interface{}) Result {
 	return RenderJsonResult{o}
 }
 
+// Render the validation errors as a 422 (Unprocessable Entity) JSON response,
+// listing the key, message and failed rule of each error.
+//
+//	if c.Validation.HasErrors() {
+//		return c.RenderValidationErrors()
+//	}
+func (c *Controller) RenderValidationErrors() Result {
+	return &ValidationErrorsResult{c.Validation.Errors}
+}
+
 // Uses encoding/xml.Marshal to return XML to the client.
 // Will serialie it using xml.Marshal
 func (c *Controller) RenderXml(o interface{}) Result {
@@ -248,6 +309,30 @@ func (c *Controller) Todo() Result {
 	})
 }
 
+// Set the ETag and Last-Modified headers of the response (either may be left
+// empty), and return a 304 (Not Modified) result if the client's copy is
+// current, per its If-None-Match or If-Modified-Since header.  Otherwise nil
+// is returned, and the action goes on to render the response:
+//
+//   if result := c.NotModifiedIf(hotel.Version, hotel.Updated); result != nil {
+//       return result
+//   }
+//   ...
+//   return c.Render(hotel)
+func (c *Controller) NotModifiedIf(etag string, lastModified time.Time) Result {
+	etag = quoteEtag(etag)
+	if etag != "" {
+		c.Response.Out.Header().Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		c.Response.Out.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if etagMatches(c.Request, etag) || notModifiedSince(c.Request, lastModified) {
+		return NotModifiedResult{}
+	}
+	return nil
+}
+
 func (c *Controller) NotFound(msg string, objs ...interface{}) Result {
 	finalText := msg
 	if len(objs) > 0 {
@@ -313,6 +398,14 @@ func (c *Controller) Redirect(val interface{}, args ...interface{}) Result {
 // using the current language defined for this controller.
 //
 // The current language is set by the i18n plugin.
+// Plural forms and named placeholders are as for revel.Message, e.g.
+//   c.Message("hotels.count", revel.MessageArgs{"count": len(hotels)})
 func (c *Controller) Message(message string, args ...interface{}) (value string) {
 	return Message(c.Request.Locale, message, args...)
 }
+
+// Return a Formatter of numbers, money, percentages and dates for the current
+// locale.
+func (c *Controller) Formatter() Formatter {
+	return Formatter{c.Request.Locale}
+}
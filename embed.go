@@ -0,0 +1,206 @@
+package revel
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The source root of the embedded files.  Apps built with "revel build -embed"
+// carry their conf, messages, views and public files (and those of Revel and
+// the modules) in the binary, and find them below this path instead of GOPATH.
+const embeddedSourcePath = "/embedded"
+
+var (
+	// Map from the slash-separated path of an embedded file, relative to the
+	// source root (e.g. "corp/sample/conf/app.conf"), to its contents.
+	embeddedFiles map[string]string
+
+	// Set of the directories containing embedded files.
+	embeddedDirs map[string]bool
+
+	// The modification time reported for all embedded files (the build time).
+	embeddedModTime time.Time
+
+	errMemoryReaddir = errors.New("directories in memory can not be listed")
+)
+
+// Register the files packed into the app binary.  This is called by the main
+// package generated for "revel build -embed", before Init.
+func RegisterEmbeddedFiles(modTime int64, files map[string]string) {
+	embeddedFiles = files
+	embeddedModTime = time.Unix(modTime, 0)
+	embeddedDirs = map[string]bool{}
+	for name := range files {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			embeddedDirs[dir] = true
+		}
+	}
+}
+
+// Return true if the app was built with its files embedded in the binary.
+func Embedded() bool {
+	return embeddedFiles != nil
+}
+
+// Open the named file for reading, from the embedded files if the app was
+// built with them (and the name is below the embedded source root), or else
+// from the filesystem.
+func OpenFile(name string) (http.File, error) {
+	if key, ok := embeddedKey(name); ok {
+		info, err := embeddedStat(name, key)
+		if err != nil {
+			return nil, err
+		}
+		return &memoryFile{strings.NewReader(embeddedFiles[key]), info}, nil
+	}
+	return os.Open(name)
+}
+
+// Return the FileInfo of the named file, which may be embedded (see OpenFile).
+func StatFile(name string) (os.FileInfo, error) {
+	if key, ok := embeddedKey(name); ok {
+		return embeddedStat(name, key)
+	}
+	return os.Stat(name)
+}
+
+// Read the contents of the named file, which may be embedded (see OpenFile).
+func ReadFile(name string) ([]byte, error) {
+	if key, ok := embeddedKey(name); ok {
+		contents, ok := embeddedFiles[key]
+		if !ok {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		return []byte(contents), nil
+	}
+	return ioutil.ReadFile(name)
+}
+
+// Walk the file tree rooted at root like filepath.Walk, which may be embedded
+// (see OpenFile).
+func WalkFiles(root string, walkFn filepath.WalkFunc) error {
+	key, ok := embeddedKey(root)
+	if !ok {
+		return filepath.Walk(root, walkFn)
+	}
+
+	info, err := embeddedStat(root, key)
+	if err != nil {
+		return walkFn(root, nil, err)
+	}
+	return walkEmbedded(root, key, info, walkFn)
+}
+
+func walkEmbedded(name, key string, info os.FileInfo, walkFn filepath.WalkFunc) error {
+	err := walkFn(name, info, nil)
+	if err != nil {
+		if info.IsDir() && err == filepath.SkipDir {
+			return nil
+		}
+		return err
+	}
+
+	if !info.IsDir() {
+		return nil
+	}
+
+	for _, child := range embeddedChildren(key) {
+		childInfo, _ := embeddedStat(child, key+"/"+child)
+		err = walkEmbedded(filepath.Join(name, child), key+"/"+child, childInfo, walkFn)
+		if err != nil {
+			if !childInfo.IsDir() && err == filepath.SkipDir {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Return the sorted names of the files and directories in the embedded dir.
+func embeddedChildren(dir string) []string {
+	var names []string
+	prefix := dir + "/"
+	add := func(key string) {
+		if strings.HasPrefix(key, prefix) && !strings.Contains(key[len(prefix):], "/") {
+			names = append(names, key[len(prefix):])
+		}
+	}
+	for key := range embeddedFiles {
+		add(key)
+	}
+	for key := range embeddedDirs {
+		add(key)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Return the key of the embedded file at the given path, and true if the path
+// is below the embedded source root of an app built with embedded files.
+func embeddedKey(name string) (string, bool) {
+	if embeddedFiles == nil {
+		return "", false
+	}
+	name = path.Clean(filepath.ToSlash(name))
+	if !strings.HasPrefix(name, embeddedSourcePath+"/") {
+		return "", false
+	}
+	return name[len(embeddedSourcePath)+1:], true
+}
+
+func embeddedStat(name, key string) (os.FileInfo, error) {
+	if contents, ok := embeddedFiles[key]; ok {
+		return &memoryFileInfo{path.Base(key), int64(len(contents)), embeddedModTime, false}, nil
+	}
+	if embeddedDirs[key] {
+		return &memoryFileInfo{path.Base(key), 0, embeddedModTime, true}, nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+// A file held in memory (embedded in the binary, or an asset bundle), opened
+// for reading.  It implements http.File.
+type memoryFile struct {
+	*strings.Reader
+	info os.FileInfo
+}
+
+func (f *memoryFile) Close() error {
+	return nil
+}
+
+func (f *memoryFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, errMemoryReaddir
+}
+
+func (f *memoryFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+type memoryFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+func (info *memoryFileInfo) Name() string       { return info.name }
+func (info *memoryFileInfo) Size() int64        { return info.size }
+func (info *memoryFileInfo) ModTime() time.Time { return info.modTime }
+func (info *memoryFileInfo) IsDir() bool        { return info.isDir }
+func (info *memoryFileInfo) Sys() interface{}   { return nil }
+
+func (info *memoryFileInfo) Mode() os.FileMode {
+	if info.isDir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
@@ -0,0 +1,67 @@
+package revel
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func registerTestEmbeddedFiles() {
+	RegisterEmbeddedFiles(1370000000, map[string]string{
+		"corp/sample/conf/app.conf":              "app.name=sample\n[prod]\nhttp.port=9001\n",
+		"corp/sample/app/views/Hotels/Show.html": "<h1>{{.name}}</h1>",
+		"corp/sample/app/views/errors/404.html":  "Not found",
+		"corp/sample/public/css/app.css":         "body {}",
+	})
+}
+
+func TestEmbeddedFiles(t *testing.T) {
+	registerTestEmbeddedFiles()
+	defer func() { embeddedFiles, embeddedDirs = nil, nil }()
+
+	base := embeddedSourcePath + "/corp/sample"
+	if contents, err := ReadFile(base + "/public/css/app.css"); err != nil || string(contents) != "body {}" {
+		t.Errorf("Unexpected embedded file contents %q (%v)", contents, err)
+	}
+	if info, err := StatFile(base + "/public/css/app.css"); err != nil || info.Size() != 7 || info.ModTime().Unix() != 1370000000 {
+		t.Errorf("Unexpected embedded file info %v (%v)", info, err)
+	}
+	if _, err := StatFile(base + "/public/missing.css"); !os.IsNotExist(err) {
+		t.Errorf("Expected a missing embedded file not to exist, got %v", err)
+	}
+	if !DirExists(base+"/app/views") || DirExists(base+"/app/missing") {
+		t.Error("Expected the embedded directories to exist")
+	}
+
+	var walked []string
+	WalkFiles(base+"/app/views", func(path string, info os.FileInfo, err error) error {
+		walked = append(walked, filepath.ToSlash(path[len(base):]))
+		return nil
+	})
+	if actual, expected := strings.Join(walked, " "),
+		"/app/views /app/views/Hotels /app/views/Hotels/Show.html /app/views/errors /app/views/errors/404.html"; actual != expected {
+		t.Errorf("Expected to walk %s, got %s", expected, actual)
+	}
+
+	conf, err := readConfigFile(base + "/conf/app.conf")
+	if err != nil {
+		t.Fatalf("Failed to read embedded config: %s", err)
+	}
+	if port, _ := conf.Int("prod", "http.port"); port != 9001 {
+		t.Errorf("Expected http.port 9001 from the embedded config, got %d", port)
+	}
+
+	loader := NewTemplateLoader([]string{base + "/app/views"})
+	if err := loader.Refresh(); err != nil {
+		t.Fatalf("Failed to load embedded templates: %s", err)
+	}
+	if actual := renderTestTemplate(t, loader, "Hotels/Show.html", map[string]interface{}{"name": "Hilton"}); actual != "<h1>Hilton</h1>" {
+		t.Errorf("Unexpected embedded template output %s", actual)
+	}
+
+	// Files outside of the embedded source root are read from the filesystem.
+	if _, err := StatFile(testViewsPath); err != nil {
+		t.Errorf("Expected to find %s on the filesystem: %s", testViewsPath, err)
+	}
+}
@@ -0,0 +1,153 @@
+package revel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// An event sent to the client over a Server-Sent Events stream.
+type ServerEvent struct {
+	// The id of the event, which the client sends back in the Last-Event-ID
+	// header when it reconnects.  (Optional)
+	Id string
+	// The type of the event, dispatched to the client's listeners for it.
+	// Events without a name are "message" events.  (Optional)
+	Name string
+	// The data of the event.  Strings and []byte are sent as they are; other
+	// values are encoded as JSON.
+	Data interface{}
+	// If set, tells the client how long to wait before reconnecting.
+	Retry time.Duration
+}
+
+// The default interval between keep-alive comments on an event stream.
+const DefaultEventStreamKeepAlive = 15 * time.Second
+
+// This result sends the events from its channel to the client, as a
+// text/event-stream, until the channel is closed or the client disconnects:
+//
+//   func (c Clock) Ticks() revel.Result {
+//       events := make(chan revel.ServerEvent)
+//       done := make(chan bool)
+//       go func() {
+//           defer close(events)
+//           for tick := 1; ; tick++ {
+//               select {
+//               case events <- revel.ServerEvent{Id: strconv.Itoa(tick), Data: time.Now()}:
+//                   time.Sleep(time.Second)
+//               case <-done:
+//                   return
+//               }
+//           }
+//       }()
+//       return c.RenderEventStream(events, func() { close(done) })
+//   }
+//
+// (The chat sample resumes the stream from c.LastEventId())
+//
+// Between events, keep-alive comments are sent to stop proxies from timing out
+// the connection.
+type EventStreamResult struct {
+	Events <-chan ServerEvent
+	// If set, tells the client how long to wait before reconnecting.
+	Retry time.Duration
+	// The interval between keep-alive comments.  Defaults to
+	// DefaultEventStreamKeepAlive; negative to send none.
+	KeepAlive time.Duration
+	// Called when the stream ends, for any reason, e.g. to unsubscribe from
+	// the source of the events.  (Optional)
+	OnClose func()
+}
+
+func (r *EventStreamResult) Apply(req *Request, resp *Response) {
+	if r.OnClose != nil {
+		defer r.OnClose()
+	}
+
+	resp.Out.Header().Del("Content-Length")
+	resp.Out.Header().Set("Cache-Control", "no-cache")
+	// Stop nginx from buffering the stream.
+	resp.Out.Header().Set("X-Accel-Buffering", "no")
+	resp.WriteHeader(http.StatusOK, "text/event-stream")
+
+	w := newStreamWriter(resp)
+	if r.Retry > 0 {
+		fmt.Fprintf(w, "retry: %d\n\n", r.Retry/time.Millisecond)
+	}
+	w.Flush()
+
+	var keepAlive <-chan time.Time
+	interval := r.KeepAlive
+	if interval == 0 {
+		interval = DefaultEventStreamKeepAlive
+	}
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	for {
+		var err error
+		select {
+		case event, ok := <-r.Events:
+			if !ok {
+				return
+			}
+			_, err = w.Write(event.encode())
+		case <-keepAlive:
+			_, err = w.Write([]byte(": keep-alive\n\n"))
+		case <-w.Closed():
+			err = ErrClientDisconnected
+		}
+		if err != nil {
+			TRACE.Println("Event stream closed", req.URL.Path, ":", err)
+			return
+		}
+		w.Flush()
+	}
+}
+
+// Return the event in the text/event-stream format.
+func (e ServerEvent) encode() []byte {
+	var b bytes.Buffer
+	if e.Id != "" {
+		fmt.Fprintf(&b, "id: %s\n", stripNewlines(e.Id))
+	}
+	if e.Name != "" {
+		fmt.Fprintf(&b, "event: %s\n", stripNewlines(e.Name))
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry/time.Millisecond)
+	}
+
+	var data string
+	switch d := e.Data.(type) {
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	case nil:
+	default:
+		dataBytes, err := json.Marshal(d)
+		if err != nil {
+			ERROR.Println("Failed to encode event data:", err)
+		}
+		data = string(dataBytes)
+	}
+	// Each line of the data goes on its own data field.
+	data = strings.Replace(data, "\r\n", "\n", -1)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return b.Bytes()
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
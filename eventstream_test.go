@@ -0,0 +1,93 @@
+package revel
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerEventEncoding(t *testing.T) {
+	var eventTests = []struct {
+		event    ServerEvent
+		expected string
+	}{
+		{ServerEvent{Data: "hello"}, "data: hello\n\n"},
+		{ServerEvent{Id: "42", Name: "join", Data: "a\nb"}, "id: 42\nevent: join\ndata: a\ndata: b\n\n"},
+		{ServerEvent{Data: map[string]int{"count": 1}, Retry: 3 * time.Second}, "retry: 3000\ndata: {\"count\":1}\n\n"},
+		// Newlines can not break out of the id field.
+		{ServerEvent{Id: "1\ndata: x", Data: []byte("y")}, "id: 1data: x\ndata: y\n\n"},
+	}
+	for _, test := range eventTests {
+		if actual := string(test.event.encode()); actual != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, actual)
+		}
+	}
+}
+
+func TestEventStreamResult(t *testing.T) {
+	req, recorder := newStreamTestRequest()
+	events := make(chan ServerEvent, 2)
+	events <- ServerEvent{Id: "1", Data: "first"}
+	events <- ServerEvent{Id: "2", Data: "second"}
+	close(events)
+
+	closed := false
+	result := &EventStreamResult{
+		Events:  events,
+		Retry:   time.Second,
+		OnClose: func() { closed = true },
+	}
+	result.Apply(req, NewResponse(recorder))
+
+	if !closed {
+		t.Error("Expected OnClose to be called")
+	}
+	if contentType := recorder.HeaderMap.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %s", contentType)
+	}
+	if cacheControl := recorder.HeaderMap.Get("Cache-Control"); cacheControl != "no-cache" {
+		t.Errorf("Expected no-cache, got %s", cacheControl)
+	}
+	expected := "retry: 1000\n\nid: 1\ndata: first\n\nid: 2\ndata: second\n\n"
+	if body := recorder.Body.String(); body != expected {
+		t.Errorf("Expected %q, got %q", expected, body)
+	}
+}
+
+func TestEventStreamKeepAliveAndDisconnect(t *testing.T) {
+	req, recorder := newStreamTestRequest()
+	closed := make(chan bool, 1)
+	result := &EventStreamResult{
+		Events:    make(chan ServerEvent),
+		KeepAlive: 10 * time.Millisecond,
+		OnClose:   func() { closed <- true },
+	}
+	go func() {
+		time.Sleep(35 * time.Millisecond)
+		recorder.closed <- true
+	}()
+	result.Apply(req, NewResponse(recorder))
+
+	select {
+	case <-closed:
+	default:
+		t.Error("Expected OnClose to be called on disconnect")
+	}
+	if body := recorder.Body.String(); !strings.HasPrefix(body, ": keep-alive\n\n") {
+		t.Errorf("Expected keep-alive comments, got %q", body)
+	}
+}
+
+func TestLastEventId(t *testing.T) {
+	httpReq, _ := http.NewRequest("GET", "/events?lastEventId=7", nil)
+	req := NewRequest(httpReq)
+	c := &Controller{Request: req, Params: ParseParams(req)}
+	if id := c.LastEventId(); id != "7" {
+		t.Errorf("Expected the lastEventId param, got %s", id)
+	}
+	httpReq.Header.Set("Last-Event-ID", "9")
+	if id := c.LastEventId(); id != "9" {
+		t.Errorf("Expected the Last-Event-ID header, got %s", id)
+	}
+}
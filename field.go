@@ -1,8 +1,15 @@
 package revel
 
 import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Field represents a data fieid that may be collected in a web form.
@@ -70,6 +77,9 @@ func (f *Field) Value() interface{} {
 		if val.Kind() == reflect.Ptr {
 			val = val.Elem()
 		}
+		if val.Kind() != reflect.Struct {
+			return ""
+		}
 		val = val.FieldByName(pieces[i])
 		if !val.IsValid() {
 			return ""
@@ -86,3 +96,317 @@ func (f *Field) ErrorClass() string {
 	}
 	return ""
 }
+
+// Return the value to pre-fill the field with: the flashed value if there is
+// one, or else the current value in the RenderArgs.
+func (f *Field) InputValue() string {
+	if v, ok := f.renderArgs["flash"].(map[string]string)[f.Name]; ok {
+		return v
+	}
+
+	switch v := f.Value().(type) {
+	case nil:
+		return ""
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(DateFormat)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Return all of the validation error messages for this field.
+func (f *Field) ErrorMessages() []string {
+	var messages []string
+	if c, ok := f.renderArgs["Controller"].(*Controller); ok && c.Validation != nil {
+		for _, err := range c.Validation.Errors {
+			if err.Key == f.Name && err.Message != "" {
+				messages = append(messages, err.Message)
+			}
+		}
+	}
+	if len(messages) == 0 && f.Error != nil && f.Error.Message != "" {
+		messages = append(messages, f.Error.Message)
+	}
+	return messages
+}
+
+// Return the validators declared for this field.
+// They are found by running the Validate method of the field's render arg
+// (e.g. "user" for "user.Name") without checking any values, and picking the
+// rules keyed on the field name.  So, that requires the receiver of Validate
+// to be named like the render arg:
+//
+//	func (user User) Validate(v *revel.Validation) {
+//		v.Required(user.Name) // Declared for "user.Name"
+//	}
+func (f *Field) Validators() []Validator {
+	pieces := strings.Split(f.Name, ".")
+	var validators []Validator
+	for _, rule := range declaredRules(f.renderArgs, pieces[0]) {
+		if rule.Error.Key == f.Name {
+			validators = append(validators, rule.Validator)
+		}
+	}
+	return validators
+}
+
+// The render arg caching the rules declared by each render arg, so that
+// Validate is run once per render, rather than once per field.
+const declaredRulesRenderArg = "_declaredRules"
+
+// Return the validation rules declared by the named render arg.
+func declaredRules(renderArgs map[string]interface{}, name string) []*validationRule {
+	if renderArgs == nil {
+		return nil
+	}
+	cache, ok := renderArgs[declaredRulesRenderArg].(map[string][]*validationRule)
+	if !ok {
+		cache = make(map[string][]*validationRule)
+		renderArgs[declaredRulesRenderArg] = cache
+	}
+	if rules, found := cache[name]; found {
+		return rules
+	}
+	rules := recordRules(renderArgs[name])
+	cache[name] = rules
+	return rules
+}
+
+// Run the Validate method of the arg, if it has one, recording its rules.
+func recordRules(arg interface{}) []*validationRule {
+	if arg == nil {
+		return nil
+	}
+
+	validatable, ok := arg.(Validatable)
+	if !ok {
+		// Validate may have been declared on the pointer receiver.
+		val := reflect.ValueOf(arg)
+		ptr := reflect.New(val.Type())
+		ptr.Elem().Set(val)
+		if validatable, ok = ptr.Interface().(Validatable); !ok {
+			return nil
+		}
+	}
+
+	v := &Validation{recording: true}
+	validatable.Validate(v)
+	return v.rules
+}
+
+// Form helpers
+//
+// These render the common form elements for a Field: they are pre-filled
+// with the flashed or current value, get the ERROR_CLASS if the field has an
+// error, and carry the HTML5 constraint attributes (required, maxlength, ...)
+// derived from the validation rules declared for the field.
+// Extra attributes may be given as name / value pairs.
+//
+//	{{with $field := field "user.Name" .}}
+//	  {{label $field "Name"}}
+//	  {{input $field "text" "size" "20"}}
+//	  {{fieldErrors $field}}
+//	{{end}}
+
+// {{input $field "text" ["name" "value" ...]}}
+func formInput(f *Field, inputType string, attrs ...string) template.HTML {
+	constraints, typeOverride := constraintAttrs(f.Validators())
+	if inputType == "text" && typeOverride != "" {
+		inputType = typeOverride
+	}
+	if inputType == "password" {
+		return template.HTML(fmt.Sprintf(`<input type="%s" id="%s" name="%s"%s>`,
+			html.EscapeString(inputType), html.EscapeString(f.Id()), html.EscapeString(f.Name),
+			fieldAttrs(f, constraints, attrs)))
+	}
+	return template.HTML(fmt.Sprintf(`<input type="%s" id="%s" name="%s" value="%s"%s>`,
+		html.EscapeString(inputType), html.EscapeString(f.Id()), html.EscapeString(f.Name),
+		html.EscapeString(f.InputValue()), fieldAttrs(f, constraints, attrs)))
+}
+
+// {{textarea $field ["name" "value" ...]}}
+func formTextarea(f *Field, attrs ...string) template.HTML {
+	constraints, _ := constraintAttrs(f.Validators())
+	return template.HTML(fmt.Sprintf(`<textarea id="%s" name="%s"%s>%s</textarea>`,
+		html.EscapeString(f.Id()), html.EscapeString(f.Name),
+		fieldAttrs(f, constraints, attrs), html.EscapeString(f.InputValue())))
+}
+
+// {{checkbox $field "true" ["name" "value" ...]}}
+func formCheckbox(f *Field, val string, attrs ...string) template.HTML {
+	checked := ""
+	if f.InputValue() == val {
+		checked = " checked"
+	}
+	constraints, _ := constraintAttrs(f.Validators())
+	return template.HTML(fmt.Sprintf(`<input type="checkbox" id="%s" name="%s" value="%s"%s%s>`,
+		html.EscapeString(f.Id()), html.EscapeString(f.Name), html.EscapeString(val),
+		checked, fieldAttrs(f, constraints, attrs)))
+}
+
+// Options may be given as a slice (each element is both value and label), or
+// as a map from value to label (sorted by value).
+// {{select $field .countries ["name" "value" ...]}}
+func formSelect(f *Field, options interface{}, attrs ...string) template.HTML {
+	var values, labels []string
+	switch v := reflect.ValueOf(options); v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			value := fmt.Sprint(v.Index(i).Interface())
+			values = append(values, value)
+			labels = append(labels, value)
+		}
+	case reflect.Map:
+		byValue := make(map[string]string)
+		for _, key := range v.MapKeys() {
+			value := fmt.Sprint(key.Interface())
+			byValue[value] = fmt.Sprint(v.MapIndex(key).Interface())
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		for _, value := range values {
+			labels = append(labels, byValue[value])
+		}
+	default:
+		ERROR.Println("select: unexpected type for options:", v)
+	}
+
+	current := f.InputValue()
+	constraints, _ := constraintAttrs(f.Validators())
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<select id="%s" name="%s"%s>`,
+		html.EscapeString(f.Id()), html.EscapeString(f.Name), fieldAttrs(f, constraints, attrs))
+	for i, value := range values {
+		selected := ""
+		if value == current {
+			selected = " selected"
+		}
+		fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`,
+			html.EscapeString(value), selected, html.EscapeString(labels[i]))
+	}
+	b.WriteString("</select>")
+	return template.HTML(b.String())
+}
+
+// {{label $field "Check In Date"}}
+func formLabel(f *Field, text string) template.HTML {
+	class := ""
+	if f.Error != nil {
+		class = fmt.Sprintf(` class="%s"`, html.EscapeString(ERROR_CLASS))
+	}
+	return template.HTML(fmt.Sprintf(`<label for="%s"%s>%s</label>`,
+		html.EscapeString(f.Id()), class, html.EscapeString(text)))
+}
+
+// {{fieldErrors $field}}
+func formFieldErrors(f *Field) template.HTML {
+	var b bytes.Buffer
+	for _, message := range f.ErrorMessages() {
+		fmt.Fprintf(&b, `<span class="error">%s</span>`, html.EscapeString(message))
+	}
+	return template.HTML(b.String())
+}
+
+// Render the constraint attributes and the extra attributes of a form element,
+// adding the ERROR_CLASS to its class if the field has an error.
+func fieldAttrs(f *Field, constraints []string, attrs []string) string {
+	if len(attrs)%2 != 0 {
+		ERROR.Println("Form helper attributes must be given as name / value pairs:", attrs)
+		attrs = attrs[:len(attrs)-1]
+	}
+
+	class := f.ErrorClass()
+	var b bytes.Buffer
+	for _, constraint := range constraints {
+		b.WriteString(" " + constraint)
+	}
+	for i := 0; i < len(attrs); i += 2 {
+		if attrs[i] == "class" {
+			class = strings.TrimSpace(attrs[i+1] + " " + class)
+			continue
+		}
+		fmt.Fprintf(&b, ` %s="%s"`, html.EscapeString(attrs[i]), html.EscapeString(attrs[i+1]))
+	}
+	if class != "" {
+		fmt.Fprintf(&b, ` class="%s"`, html.EscapeString(class))
+	}
+	return b.String()
+}
+
+// Translate validators to HTML5 constraint attributes, e.g. `maxlength="15"`.
+// Also returns the input type implied by the validators ("email", "url"), if any.
+func constraintAttrs(validators []Validator) (attrs []string, inputType string) {
+	add := func(name string, value interface{}) {
+		if value == nil {
+			attrs = append(attrs, name)
+			return
+		}
+		attrs = append(attrs, fmt.Sprintf(`%s="%s"`, name, html.EscapeString(fmt.Sprint(value))))
+	}
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	for _, validator := range validators {
+		switch v := validator.(type) {
+		case Required:
+			add("required", nil)
+		case RequiredIf:
+			if v.Condition {
+				add("required", nil)
+			}
+		case RequiredUnless:
+			if !v.Condition {
+				add("required", nil)
+			}
+		case MinSize:
+			add("minlength", v.Min)
+		case MaxSize:
+			add("maxlength", v.Max)
+		case Length:
+			add("minlength", v.N)
+			add("maxlength", v.N)
+		case Min:
+			add("min", v.Min)
+		case Max:
+			add("max", v.Max)
+		case Range:
+			add("min", v.Min.Min)
+			add("max", v.Max.Max)
+		case MinFloat:
+			add("min", formatFloat(v.Min))
+		case MaxFloat:
+			add("max", formatFloat(v.Max))
+		case RangeFloat:
+			add("min", formatFloat(v.MinFloat.Min))
+			add("max", formatFloat(v.MaxFloat.Max))
+		case Email:
+			inputType = "email"
+		case URL:
+			inputType = "url"
+		case Match:
+			if v.Regexp != nil && isHtmlPattern(v.Regexp.String()) {
+				add("pattern", v.Regexp.String())
+			}
+		}
+	}
+	return attrs, inputType
+}
+
+// Whether the regexp may be used as an HTML pattern: the pattern must match
+// the whole value, so the regexp must be anchored (^...$), and it must not use
+// the syntax of Go regexps that JavaScript does not have.
+func isHtmlPattern(expr string) bool {
+	if !strings.HasPrefix(expr, "^") || !strings.HasSuffix(expr, "$") || strings.HasSuffix(expr, `\$`) {
+		return false
+	}
+	for _, goOnly := range []string{`(?`, `\A`, `\z`, `\C`, `\Q`, `\p`, `\P`, `[[:`} {
+		if strings.Contains(expr, goOnly) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package revel
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type formUser struct {
+	Name    string
+	Email   string
+	Age     int
+	Country string
+	Admin   bool
+}
+
+func (user formUser) Validate(v *Validation) {
+	v.Check(user.Name, Required{}, MaxSize{15}, Match{regexp.MustCompile("^\\w*$")}).Key("user.Name")
+	v.Email(user.Email).Key("user.Email")
+	v.Range(user.Age, 18, 120).Key("user.Age")
+}
+
+func formRenderArgs() map[string]interface{} {
+	return map[string]interface{}{
+		"user":   formUser{Name: "Rob <3", Age: 30, Admin: true},
+		"flash":  map[string]string{"user.Country": "nl"},
+		"errors": map[string]*ValidationError{"user.Name": {Message: "Too <long>", Key: "user.Name"}},
+	}
+}
+
+func TestFormInput(t *testing.T) {
+	args := formRenderArgs()
+
+	actual := string(formInput(NewField("user.Name", args), "text", "class", "wide"))
+	expected := `<input type="text" id="user_Name" name="user.Name" value="Rob &lt;3" required maxlength="15" pattern="^\w*$" class="wide hasError">`
+	if actual != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+
+	actual = string(formInput(NewField("user.Email", args), "text"))
+	expected = `<input type="email" id="user_Email" name="user.Email" value="">`
+	if actual != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+
+	actual = string(formInput(NewField("user.Age", args), "number"))
+	expected = `<input type="number" id="user_Age" name="user.Age" value="30" min="18" max="120">`
+	if actual != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+}
+
+func TestFormSelectAndCheckbox(t *testing.T) {
+	args := formRenderArgs()
+
+	actual := string(formSelect(NewField("user.Country", args), map[string]string{"us": "United States", "nl": "Netherlands"}))
+	expected := `<select id="user_Country" name="user.Country"><option value="nl" selected>Netherlands</option><option value="us">United States</option></select>`
+	if actual != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+
+	actual = string(formSelect(NewField("user.Age", args), []int{20, 30}))
+	if !strings.Contains(actual, `<option value="30" selected>30</option>`) {
+		t.Errorf("Expected option 30 to be selected: %s", actual)
+	}
+
+	actual = string(formCheckbox(NewField("user.Admin", args), "true"))
+	expected = `<input type="checkbox" id="user_Admin" name="user.Admin" value="true" checked>`
+	if actual != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+}
+
+func TestFormLabelAndErrors(t *testing.T) {
+	args := formRenderArgs()
+
+	if actual := string(formLabel(NewField("user.Name", args), "Name")); actual != `<label for="user_Name" class="hasError">Name</label>` {
+		t.Errorf("Unexpected label: %s", actual)
+	}
+	if actual := string(formFieldErrors(NewField("user.Name", args))); actual != `<span class="error">Too &lt;long&gt;</span>` {
+		t.Errorf("Unexpected errors: %s", actual)
+	}
+	if actual := string(formFieldErrors(NewField("user.Age", args))); actual != "" {
+		t.Errorf("Expected no errors, got: %s", actual)
+	}
+}
+
+type countingValidatable struct {
+	calls *int
+}
+
+func (c countingValidatable) Validate(v *Validation) {
+	*c.calls++
+	v.Required("").Key("counting.Name")
+}
+
+func TestFieldValidatorsAreDeclaredOncePerRender(t *testing.T) {
+	calls := 0
+	args := map[string]interface{}{
+		"counting": countingValidatable{&calls},
+		"errors":   map[string]*ValidationError{},
+	}
+	for _, name := range []string{"counting.Name", "counting.Email", "counting.Name"} {
+		NewField(name, args).Validators()
+	}
+	if calls != 1 {
+		t.Errorf("Expected Validate to run once, got %d", calls)
+	}
+	if validators := NewField("counting.Name", args).Validators(); len(validators) != 1 {
+		t.Errorf("Expected one validator, got %v", validators)
+	}
+}
+
+func TestHtmlPattern(t *testing.T) {
+	var patternTests = []struct {
+		expr    string
+		pattern bool
+	}{
+		{`^\w*$`, true},
+		{`^[a-z]+-\d{2}$`, true},
+		{`\w*`, false},
+		{`^\w*`, false},
+		{`^\w*\$`, false},
+		{`(?i)^abc$`, false},
+		{`^\pL+$`, false},
+		{`^[[:alpha:]]+$`, false},
+		{`\Aabc\z`, false},
+	}
+	for _, test := range patternTests {
+		if isHtmlPattern(test.expr) != test.pattern {
+			t.Errorf("%s: expected HTML pattern %v", test.expr, test.pattern)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package revel
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Formatter formats numbers, amounts of money, percentages, dates and
+// relative times for a locale, according to the patterns in its messages file:
+//
+//   format.number.decimal=,
+//   format.number.group=.
+//   format.currency=¤ #              (¤ is the currency symbol, # the amount)
+//   format.currency.decimals=2
+//   format.percent=#%
+//   format.date.short=02-01-06       (as Go time layouts)
+//   format.date.medium=2 Jan 2006
+//   format.date.long=2 January 2006
+//   format.date=02-01-2006           (for date and datetime, else those of app.conf)
+//   format.datetime=02-01-2006 15:04
+//   currency.EUR=€
+//   date.January=januari             (and so on, for the names of months and days)
+//   time.now=zojuist
+//   time.ago=%s geleden
+//   time.later=over %s
+//   time.minutes.one=%d minuut       (and so on, for seconds, hours, days, months and years)
+//   time.minutes.other=%d minuten
+//
+// Patterns missing from the messages are taken to be the English ones.
+//
+// In templates, the formatting functions take the render args first:
+//
+//   {{formatNumber . 1234.5}}            => 1.234,5
+//   {{formatCurrency . 9.95 "EUR"}}      => € 9,95
+//   {{formatPercent . 0.25}}             => 25%
+//   {{formatDate . .booking.CheckInDate "long"}}
+//   {{date .booking.CheckInDate}}        => 09-07-1982
+//   {{relativeTime . .post.Created}}     => 3 minuten geleden
+type Formatter struct {
+	Locale string
+}
+
+// The patterns used when the messages do not have them.
+var defaultFormats = map[string]string{
+	"format.number.decimal":    ".",
+	"format.number.group":      ",",
+	"format.currency":          "¤#",
+	"format.currency.decimals": "2",
+	"format.percent":           "#%",
+	"format.date.short":        "1/2/06",
+	"format.date.medium":       "Jan 2, 2006",
+	"format.date.long":         "January 2, 2006",
+	"time.now":                 "just now",
+	"time.ago":                 "%s ago",
+	"time.later":               "in %s",
+	"time.seconds.one":         "%d second",
+	"time.seconds.other":       "%d seconds",
+	"time.minutes.one":         "%d minute",
+	"time.minutes.other":       "%d minutes",
+	"time.hours.one":           "%d hour",
+	"time.hours.other":         "%d hours",
+	"time.days.one":            "%d day",
+	"time.days.other":          "%d days",
+	"time.months.one":          "%d month",
+	"time.months.other":        "%d months",
+	"time.years.one":           "%d year",
+	"time.years.other":         "%d years",
+}
+
+// Return the pattern for the given key from the locale's messages.
+func (f Formatter) pattern(key string) string {
+	if value, found := findMessage(f.Locale, key, false); found {
+		return value
+	}
+	return defaultFormats[key]
+}
+
+// Format the number with the locale's decimal and grouping separators.
+// Floats are given with the given number of decimals, or else as few as
+// needed (at most 3); integers are given without decimals.
+//   Formatter{"en"}.Number(1234567.891) => 1,234,567.891
+func (f Formatter) Number(value interface{}, decimals ...int) string {
+	digits := -1
+	if len(decimals) > 0 {
+		digits = decimals[0]
+	}
+	return f.formatNumber(value, digits)
+}
+
+// Format the amount of money in the given currency (e.g. "EUR"), using the
+// locale's symbol for it (or the code itself) and its currency pattern.
+//   Formatter{"en"}.Currency(1234.5, "USD") => $1,234.50
+func (f Formatter) Currency(value interface{}, currency string) string {
+	decimals, err := strconv.Atoi(f.pattern("format.currency.decimals"))
+	if err != nil {
+		decimals = 2
+	}
+	amount := f.formatNumber(value, decimals)
+	negative := strings.HasPrefix(amount, "-")
+	amount = strings.TrimPrefix(amount, "-")
+
+	symbol, found := findMessage(f.Locale, "currency."+currency, false)
+	if !found {
+		symbol = currencySymbols[currency]
+		if symbol == "" {
+			symbol = currency
+		}
+	}
+
+	result := strings.Replace(strings.Replace(f.pattern("format.currency"), "#", amount, 1), "¤", symbol, 1)
+	if negative {
+		result = "-" + result
+	}
+	return result
+}
+
+// Symbols of some common currencies, used if the messages do not have them.
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+}
+
+// Format the ratio (e.g. 0.25) as a percentage, with the given number of
+// decimals (0 by default).
+//   Formatter{"en"}.Percent(0.255, 1) => 25.5%
+func (f Formatter) Percent(value interface{}, decimals ...int) string {
+	digits := 0
+	if len(decimals) > 0 {
+		digits = decimals[0]
+	}
+	ratio, ok := toFloat(value)
+	if !ok {
+		ERROR.Printf("formatPercent: unexpected type %T", value)
+		return ""
+	}
+	return strings.Replace(f.pattern("format.percent"), "#", f.formatNumber(ratio*100, digits), 1)
+}
+
+// Format the date in the given style: "short", "medium" (the default) or "long".
+//   Formatter{"en"}.Date(t, "long") => July 9, 1982
+func (f Formatter) Date(t time.Time, style ...string) string {
+	dateStyle := "medium"
+	if len(style) > 0 && style[0] != "" {
+		dateStyle = style[0]
+	}
+	layout := f.pattern("format.date." + dateStyle)
+	if layout == "" {
+		ERROR.Printf("formatDate: unknown style %s", dateStyle)
+		layout = f.pattern("format.date.medium")
+	}
+	return f.formatTime(t, layout)
+}
+
+// Format the time with the Go layout, with the names of its months and days
+// taken from the locale's messages (e.g. date.July=juli).
+func (f Formatter) formatTime(t time.Time, layout string) string {
+	var formatted bytes.Buffer
+	for layout != "" {
+		i, element := nextDateName(layout)
+		formatted.WriteString(t.Format(layout[:i]))
+		if element == "" {
+			break
+		}
+		var name string
+		switch element {
+		case "January", "Jan":
+			name = t.Month().String()
+		case "Monday", "Mon":
+			name = t.Weekday().String()
+		}
+		if len(element) == 3 {
+			name = name[:3]
+		}
+		if value, found := findMessage(f.Locale, "date."+name, false); found {
+			name = value
+		}
+		formatted.WriteString(name)
+		layout = layout[i+len(element):]
+	}
+	return formatted.String()
+}
+
+// Return the locale's layout for dates (format.date), or for dates and times
+// (format.datetime), or else the app's DateFormat or DateTimeFormat.
+func (f Formatter) appDateLayout(withTime bool) string {
+	key, layout := "format.date", DateFormat
+	if withTime {
+		key, layout = "format.datetime", DateTimeFormat
+	}
+	if value, found := findMessage(f.Locale, key, false); found {
+		return value
+	}
+	return layout
+}
+
+// Return the index of the next name of a month or day in the Go layout, and
+// the layout element: "January", "Jan", "Monday" or "Mon".  (As in Go, "Jan"
+// and "Mon" followed by a lower case letter are not names)
+func nextDateName(layout string) (int, string) {
+	for i := 0; i+3 <= len(layout); i++ {
+		switch layout[i : i+3] {
+		case "Jan":
+			if strings.HasPrefix(layout[i:], "January") {
+				return i, "January"
+			}
+		case "Mon":
+			if strings.HasPrefix(layout[i:], "Monday") {
+				return i, "Monday"
+			}
+		default:
+			continue
+		}
+		if i+3 == len(layout) || layout[i+3] < 'a' || layout[i+3] > 'z' {
+			return i, layout[i : i+3]
+		}
+	}
+	return len(layout), ""
+}
+
+// Describe the time relative to now, e.g. "3 minutes ago" or "in 2 days".
+func (f Formatter) RelativeTime(t time.Time) string {
+	return f.relativeTime(t, time.Now())
+}
+
+func (f Formatter) relativeTime(t, now time.Time) string {
+	pattern := "time.ago"
+	d := now.Sub(t)
+	if d < 0 {
+		pattern = "time.later"
+		d = -d
+	}
+
+	var unit string
+	var n int64
+	switch {
+	case d < time.Minute:
+		if d < 10*time.Second {
+			return f.pattern("time.now")
+		}
+		unit, n = "seconds", int64(d/time.Second)
+	case d < time.Hour:
+		unit, n = "minutes", int64(d/time.Minute)
+	case d < 24*time.Hour:
+		unit, n = "hours", int64(d/time.Hour)
+	case d < 30*24*time.Hour:
+		unit, n = "days", int64(d/(24*time.Hour))
+	case d < 365*24*time.Hour:
+		unit, n = "months", int64(d/(30*24*time.Hour))
+	default:
+		unit, n = "years", int64(d/(365*24*time.Hour))
+	}
+
+	unitPattern := f.pattern("time." + unit + "." + PluralCategory(f.Locale, n))
+	if unitPattern == "" {
+		unitPattern = f.pattern("time." + unit + "." + PluralOther)
+	}
+	return strings.Replace(f.pattern(pattern), "%s",
+		strings.Replace(unitPattern, "%d", strconv.FormatInt(n, 10), 1), 1)
+}
+
+// Format the number with the locale's separators.
+// A negative number of digits formats floats with as few decimals as needed.
+func (f Formatter) formatNumber(value interface{}, digits int) string {
+	var formatted string
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		formatted = strconv.FormatInt(v.Int(), 10)
+		if digits > 0 {
+			formatted += "." + strings.Repeat("0", digits)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		formatted = strconv.FormatUint(v.Uint(), 10)
+		if digits > 0 {
+			formatted += "." + strings.Repeat("0", digits)
+		}
+	case reflect.Float32, reflect.Float64:
+		n := v.Float()
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return strconv.FormatFloat(n, 'f', -1, 64)
+		}
+		if digits < 0 {
+			formatted = strconv.FormatFloat(n, 'f', 3, 64)
+			formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+		} else {
+			formatted = strconv.FormatFloat(n, 'f', digits, 64)
+		}
+	default:
+		ERROR.Printf("formatNumber: unexpected type %T", value)
+		return ""
+	}
+
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign, formatted = "-", formatted[1:]
+	}
+	integer, fraction := formatted, ""
+	if dot := strings.Index(formatted, "."); dot != -1 {
+		integer, fraction = formatted[:dot], formatted[dot+1:]
+	}
+
+	// Group the digits of the integer part by thousands.
+	group := f.pattern("format.number.group")
+	for i := len(integer) - 3; i > 0; i -= 3 {
+		integer = integer[:i] + group + integer[i:]
+	}
+
+	if fraction != "" {
+		return sign + integer + f.pattern("format.number.decimal") + fraction
+	}
+	return sign + integer
+}
+
+// Return the formatter for the locale of the given render args.
+func renderArgsFormatter(renderArgs map[string]interface{}) Formatter {
+	locale, _ := renderArgs[CurrentLocaleRenderArg].(string)
+	return Formatter{locale}
+}
@@ -0,0 +1,76 @@
+package revel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFormatter(t *testing.T) {
+	loadMessages(testDataPath)
+	loadTestI18nConfig(t)
+
+	en, nl := Formatter{"en"}, Formatter{"nl"}
+	date := time.Date(1982, 7, 9, 21, 30, 0, 0, time.UTC)
+	var formatTests = []struct {
+		actual, expected string
+	}{
+		{en.Number(1234567), "1,234,567"},
+		{en.Number(-1234.5678), "-1,234.568"},
+		{en.Number(1234.5, 2), "1,234.50"},
+		{en.Number(uint8(12)), "12"},
+		{nl.Number(1234567.25), "1.234.567,25"},
+		{en.Currency(1234.5, "USD"), "$1,234.50"},
+		{en.Currency(-5, "CHF"), "-CHF5.00"},
+		{nl.Currency(1234.5, "EUR"), "€ 1.234,50"},
+		{en.Percent(0.25), "25%"},
+		{nl.Percent(0.255, 1), "25,5%"},
+		{en.Date(date), "Jul 9, 1982"},
+		{en.Date(date, "short"), "7/9/82"},
+		{nl.Date(date, "long"), "9 juli 1982"},
+		{nl.formatTime(date, "Monday 2 January"), "vrijdag 9 juli"},
+		{nl.formatTime(date.AddDate(0, -2, 0), "Maybe Jan 2, Monthly"), "Maybe mei 9, Monthly"},
+		{nl.formatTime(date, "Mon Jan"), "Fri jul"},
+		{en.relativeTime(date, date.Add(3*time.Minute)), "3 minutes ago"},
+		{en.relativeTime(date, date.Add(-49*time.Hour)), "in 2 days"},
+		{en.relativeTime(date, date.Add(time.Second)), "just now"},
+		{nl.relativeTime(date, date.Add(time.Minute)), "1 minuut geleden"},
+	}
+	for _, test := range formatTests {
+		if test.actual != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, test.actual)
+		}
+	}
+}
+
+func TestDateTemplateFuncs(t *testing.T) {
+	loadMessages(testDataPath)
+	loadTestI18nConfig(t)
+	defer func(date, datetime string) { DateFormat, DateTimeFormat = date, datetime }(DateFormat, DateTimeFormat)
+	DateFormat, DateTimeFormat = "01/02/2006", "01/02/2006 15:04"
+
+	dir, err := ioutil.TempDir("", "revel-views")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := `{{date .d}} {{.d | datetime}} {{range .ds}}{{date .}}{{end}}`
+	ioutil.WriteFile(filepath.Join(dir, "Page.html"), []byte(source), 0644)
+
+	loader := NewTemplateLoader([]string{dir})
+	if err := loader.Refresh(); err != nil {
+		t.Fatalf("Failed to load templates: %s", err)
+	}
+	date := time.Date(1982, 7, 9, 21, 30, 0, 0, time.UTC)
+	for locale, expected := range map[string]string{
+		"en": "07/09/1982 07/09/1982 21:30 07/09/1982",
+		"nl": "9 jul 1982 07/09/1982 21:30 9 jul 1982",
+	} {
+		args := map[string]interface{}{CurrentLocaleRenderArg: locale, "d": date, "ds": []time.Time{date}}
+		if actual := renderTestTemplate(t, loader, "Page.html", args); actual != expected {
+			t.Errorf("%s: expected %s, got %s", locale, expected, actual)
+		}
+	}
+}
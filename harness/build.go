@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/pyanfield/revel"
 	"go/build"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
@@ -22,6 +23,17 @@ var importErrorPattern = regexp.MustCompile("import \"([^\"]+)\": cannot find pa
 // Requires that revel.Init has been called previously.
 // Returns the path to the built binary, and an error if there was a problem building it.
 func Build() (app *App, compileError *revel.Error) {
+	return buildApp(false)
+}
+
+// Build the app like Build, but with its conf, messages, views and public files
+// (and those of Revel and the modules) embedded in the binary, so that it may
+// be deployed as a single file.
+func BuildEmbedded() (app *App, compileError *revel.Error) {
+	return buildApp(true)
+}
+
+func buildApp(embed bool) (app *App, compileError *revel.Error) {
 	sourceInfo, compileError := ProcessSource(revel.CodePaths)
 	if compileError != nil {
 		return nil, compileError
@@ -62,6 +74,18 @@ func Build() (app *App, compileError *revel.Error) {
 		revel.ERROR.Fatalf("Failed to write to main.go: %v", err)
 	}
 
+	// Create the embedded.go file, if requested.
+	if embed {
+		embeddedSource, err := embeddedFilesSource()
+		if err != nil {
+			revel.ERROR.Fatalf("Failed to read the files to embed: %v", err)
+		}
+		err = ioutil.WriteFile(path.Join(tmpPath, "embedded.go"), []byte(embeddedSource), 0666)
+		if err != nil {
+			revel.ERROR.Fatalf("Failed to write embedded.go: %v", err)
+		}
+	}
+
 	// Read build config.
 	buildTags := revel.Config.StringDefault("build.tags", "")
 
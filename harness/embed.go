@@ -0,0 +1,94 @@
+package harness
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/pyanfield/revel"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Return the directories to embed in the binary, mapped to the slash-separated
+// paths that they are found at below the source root at run time.
+// These are the conf, messages, views and public files of the app, the conf
+// and templates of Revel, and the views and public files of the modules.
+func embeddedDirs() map[string]string {
+	dirs := map[string]string{}
+	for _, dir := range []string{"conf", "messages", "app/views", "public"} {
+		dirs[filepath.Join(revel.BasePath, filepath.FromSlash(dir))] = path.Join(revel.ImportPath, dir)
+	}
+	for _, dir := range []string{"conf", "templates"} {
+		dirs[filepath.Join(revel.RevelPath, dir)] = path.Join(revel.REVEL_IMPORT_PATH, dir)
+	}
+	for _, module := range revel.Modules {
+		for _, dir := range []string{"app/views", "public"} {
+			dirs[filepath.Join(module.Path, filepath.FromSlash(dir))] = path.Join(module.ImportPath, dir)
+		}
+	}
+	return dirs
+}
+
+// Generate the source of a file for the main package that registers the
+// contents of the embedded directories with Revel.
+func embeddedFilesSource() (string, error) {
+	files := map[string]string{}
+	for dir, importPath := range embeddedDirs() {
+		if !revel.DirExists(dir) {
+			continue
+		}
+		err := filepath.Walk(dir, func(fileName string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			// Skip hidden files and directories (e.g. .git).
+			if strings.HasPrefix(info.Name(), ".") && fileName != dir {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				return nil
+			}
+
+			contents, err := revel.ReadFile(fileName)
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(dir, fileName)
+			files[path.Join(importPath, filepath.ToSlash(rel))] = string(contents)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, EMBEDDED_FILES_HEADER, time.Now().Unix())
+	for _, name := range names {
+		fmt.Fprintf(&b, "\t\t%s: %s,\n", strconv.Quote(name), strconv.Quote(files[name]))
+	}
+	b.WriteString("\t})\n}\n")
+	return b.String(), nil
+}
+
+const EMBEDDED_FILES_HEADER = `package main
+
+import "github.com/pyanfield/revel"
+
+func init() {
+	revel.RegisterEmbeddedFiles(%d, map[string]string{
+`
@@ -23,6 +23,7 @@ import (
 	"path"
 	"strings"
 	"sync/atomic"
+	"time"
 )
 
 var (
@@ -32,6 +33,9 @@ var (
 	lastRequestHadError int32
 )
 
+// How often the proxy flushes the app's responses to the client.
+const proxyFlushInterval = 50 * time.Millisecond
+
 // Harness reverse proxies requests to the application server.
 // It builds / runs / rebuilds / restarts the server when code is changed.
 type Harness struct {
@@ -94,6 +98,9 @@ func NewHarness() *Harness {
 		serverHost: serverUrl.String()[len("http://"):],
 		proxy:      httputil.NewSingleHostReverseProxy(serverUrl),
 	}
+	// Pass streamed responses (e.g. event streams) through as they come,
+	// rather than buffering them.
+	harness.proxy.FlushInterval = proxyFlushInterval
 	return harness
 }
 
@@ -0,0 +1,259 @@
+package harness
+
+// This file finds the message keys used by the app, for the i18n command.
+
+import (
+	"github.com/pyanfield/revel"
+	"go/ast"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A use of a message key in the app source or templates.
+type MessageKeyUse struct {
+	Key  string // e.g. "hotels.count"
+	Path string // The file using it.
+	Line int
+}
+
+// The message keys of the built-in validators, by validator type and
+// Validation method name, e.g. revel.MinSize{5} or c.Validation.MinSize(name, 5)
+var validatorMessageKeys = map[string]string{
+	"Required":       "validation.required",
+	"RequiredIf":     "validation.required",
+	"RequiredUnless": "validation.required",
+	"Min":            "validation.min",
+	"MinFloat":       "validation.min",
+	"Max":            "validation.max",
+	"MaxFloat":       "validation.max",
+	"Range":          "validation.range",
+	"RangeFloat":     "validation.range",
+	"MinSize":        "validation.minsize",
+	"MaxSize":        "validation.maxsize",
+	"Length":         "validation.length",
+	"Match":          "validation.match",
+	"Email":          "validation.email",
+	"URL":            "validation.url",
+	"IPAddr":         "validation.ipaddr",
+	"MinDate":        "validation.mindate",
+	"MaxDate":        "validation.maxdate",
+	"DateRange":      "validation.daterange",
+	"In":             "validation.in",
+	"NotIn":          "validation.notin",
+	"Alpha":          "validation.alpha",
+	"Alphanumeric":   "validation.alphanumeric",
+	"UUID":           "validation.uuid",
+	"EqualTo":        "validation.equalto",
+	"NotEqualTo":     "validation.notequalto",
+	"GreaterThan":    "validation.greaterthan",
+	"LessThan":       "validation.lessthan",
+}
+
+// Find the message keys used by the Go source under the given roots: the
+// string literals given to revel.Message, Controller.Message and
+// ValidationResult.MessageKey, or returned by the MessageKey methods of app
+// validators, and the keys of the built-in validators used.  Keys that are
+// computed can not be found.
+func FindMessageKeys(roots []string) ([]*MessageKeyUse, *revel.Error) {
+	srcInfo, compileError := ProcessSource(roots)
+	if compileError != nil {
+		return nil, compileError
+	}
+	controllerTypes := map[string]bool{revel.REVEL_IMPORT_PATH + ".Controller": true}
+	if srcInfo != nil {
+		for _, spec := range srcInfo.ControllerSpecs() {
+			controllerTypes[spec.String()] = true
+		}
+	}
+
+	var uses []*MessageKeyUse
+	compileError = walkPackages(roots, func(fset *token.FileSet, pkgImportPath, pkgPath string, pkg *ast.Package) {
+		for _, file := range pkg.Files {
+			imports := map[string]string{}
+			for _, decl := range file.Decls {
+				addImports(imports, decl, pkgPath)
+			}
+			uses = append(uses, findFileMessageKeys(fset, file, pkgImportPath, imports, controllerTypes)...)
+		}
+	})
+	return uses, compileError
+}
+
+// Find the message keys used in the file.  The controllerTypes are the
+// qualified names of the types embedding *revel.Controller.
+func findFileMessageKeys(fset *token.FileSet, file *ast.File, pkgImportPath string, imports map[string]string, controllerTypes map[string]bool) (uses []*MessageKeyUse) {
+	addUse := func(key string, pos token.Pos) {
+		position := fset.Position(pos)
+		uses = append(uses, &MessageKeyUse{key, position.Filename, position.Line})
+	}
+
+	for _, decl := range file.Decls {
+		// The receiver and parameters of funcs that are controllers or validations.
+		controllers := map[*ast.Object]bool{}
+		validations := map[*ast.Object]bool{}
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+			var fields []*ast.Field
+			if funcDecl.Recv != nil {
+				fields = append(fields, funcDecl.Recv.List...)
+				// An app validator, e.g. func (v Zip) MessageKey() (string, []interface{})
+				if funcDecl.Name.Name == "MessageKey" && funcDecl.Body != nil {
+					for _, stmt := range funcDecl.Body.List {
+						if ret, ok := stmt.(*ast.ReturnStmt); ok && len(ret.Results) > 0 {
+							if key, ok := stringLiteral(ret.Results[0]); ok {
+								addUse(key, ret.Results[0].Pos())
+							}
+						}
+					}
+				}
+			}
+			fields = append(fields, funcDecl.Type.Params.List...)
+			for _, field := range fields {
+				typeName := qualifiedTypeName(field.Type, pkgImportPath, imports)
+				for _, name := range field.Names {
+					if name.Obj == nil {
+						continue
+					}
+					if controllerTypes[typeName] {
+						controllers[name.Obj] = true
+					} else if typeName == revel.REVEL_IMPORT_PATH+".Validation" {
+						validations[name.Obj] = true
+					}
+				}
+			}
+		}
+
+		ast.Inspect(decl, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.CompositeLit:
+				// e.g. c.Validation.Check(name, revel.Required{})
+				if selExpr, ok := node.Type.(*ast.SelectorExpr); ok &&
+					qualifiedTypeName(selExpr, pkgImportPath, imports) == revel.REVEL_IMPORT_PATH+"."+selExpr.Sel.Name {
+					if key, found := validatorMessageKeys[selExpr.Sel.Name]; found {
+						addUse(key, node.Pos())
+					}
+				}
+			case *ast.CallExpr:
+				if key, pos, found := callMessageKey(node, imports, controllers, validations); found {
+					addUse(key, pos)
+				}
+			}
+			return true
+		})
+	}
+	return
+}
+
+// Return the message key used by the call, if any.
+func callMessageKey(callExpr *ast.CallExpr, imports map[string]string, controllers, validations map[*ast.Object]bool) (key string, pos token.Pos, found bool) {
+	selExpr, ok := callExpr.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+
+	// Find the argument with the key.
+	keyArg := -1
+	switch selExpr.Sel.Name {
+	case "Message":
+		// (Other Message methods, e.g. ValidationResult.Message, called on
+		// the result of a validation, take the message itself)
+		switch x := selExpr.X.(type) {
+		case *ast.Ident:
+			if imports[x.Name] == revel.REVEL_IMPORT_PATH {
+				// revel.Message(locale, key, ...)
+				keyArg = 1
+			} else if x.Obj != nil && controllers[x.Obj] {
+				// c.Message(key, ...)
+				keyArg = 0
+			}
+		case *ast.SelectorExpr:
+			if x.Sel.Name == "Controller" {
+				// c.Controller.Message(key, ...)
+				keyArg = 0
+			}
+		}
+	case "MessageKey":
+		// c.Validation.Required(name).MessageKey(key, ...)
+		keyArg = 0
+	default:
+		// c.Validation.Required(name) or v.Required(name)
+		isValidation := false
+		switch x := selExpr.X.(type) {
+		case *ast.Ident:
+			isValidation = x.Obj != nil && validations[x.Obj]
+		case *ast.SelectorExpr:
+			isValidation = x.Sel.Name == "Validation"
+		}
+		if key, found = validatorMessageKeys[selExpr.Sel.Name]; found && isValidation {
+			return key, selExpr.Sel.Pos(), true
+		}
+		return "", token.NoPos, false
+	}
+	if keyArg == -1 || len(callExpr.Args) <= keyArg {
+		return
+	}
+
+	key, found = stringLiteral(callExpr.Args[keyArg])
+	return key, callExpr.Args[keyArg].Pos(), found
+}
+
+// Return the value of the string literal.
+func stringLiteral(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(lit.Value)
+	return value, err == nil
+}
+
+// Return the qualified name of the (pointer to a) named type, e.g.
+// "github.com/pyanfield/revel.Controller" for *revel.Controller, or "" if the
+// type is not named.
+func qualifiedTypeName(expr ast.Expr, pkgImportPath string, imports map[string]string) string {
+	if starExpr, ok := expr.(*ast.StarExpr); ok {
+		expr = starExpr.X
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return pkgImportPath + "." + t.Name
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			if importPath, found := imports[x.Name]; found {
+				return importPath + "." + t.Sel.Name
+			}
+		}
+	}
+	return ""
+}
+
+// Matches the msg calls of templates, e.g. {{msg . "hotels.count" 3}}, also
+// within pipelines and parentheses.
+var templateMessagePattern = regexp.MustCompile(`(?:\{\{-?|\(|\|)\s*msg\s+[^\s"]+\s+"([^"]+)"`)
+
+// Find the message keys given to the msg function in the templates under the
+// given paths.
+func FindTemplateMessageKeys(paths []string) (uses []*MessageKeyUse) {
+	for _, basePath := range paths {
+		filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			fileBytes, err := revel.ReadFile(path)
+			if err != nil {
+				revel.ERROR.Println("Failed to read template:", path)
+				return nil
+			}
+			for i, line := range strings.Split(string(fileBytes), "\n") {
+				for _, match := range templateMessagePattern.FindAllStringSubmatch(line, -1) {
+					uses = append(uses, &MessageKeyUse{match[1], path, i + 1})
+				}
+			}
+			return nil
+		})
+	}
+	return
+}
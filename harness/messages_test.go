@@ -0,0 +1,127 @@
+package harness
+
+import (
+	"github.com/pyanfield/revel"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const messageKeysSource = `
+package test
+
+func (h Hotels) Show(id int) revel.Result {
+	// Line 5
+	title := h.Message("hotels.title")
+	count := revel.Message(h.Request.Locale, "hotels.count", 3)
+	h.Validation.Required(id).MessageKey("hotels.id.required")
+	h.Validation.Required(id).Message("Not a key")
+
+	// Line 11
+	label := h.Controller.Message("hotels.label")
+	translator.Message("Not a key either")
+	h.Message(key)
+	h.Validation.Check(id, revel.Min{1}, Zip{})
+	return h.Render(title, count, label)
+}
+
+// Line 19
+func (t Translator) Message(key string) string {
+	return t.Message("Not a controller")
+}
+
+// Line 24
+func greet(c *revel.Controller, v *revel.Validation) {
+	c.Message("greeting")
+	v.MinSize(c.Params.Get("name"), 2)
+}
+
+// Line 30
+func (z Zip) MessageKey() (string, []interface{}) {
+	return "validation.zip", nil
+}
+`
+
+var expectedMessageKeys = map[int][]string{
+	6:  {"hotels.title"},
+	7:  {"hotels.count"},
+	8:  {"validation.required", "hotels.id.required"},
+	9:  {"validation.required"},
+	12: {"hotels.label"},
+	15: {"validation.min"},
+	26: {"greeting"},
+	27: {"validation.minsize"},
+	32: {"validation.zip"},
+}
+
+func TestFindFileMessageKeys(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "messageKeysSource", messageKeysSource, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controllerTypes := map[string]bool{revel.REVEL_IMPORT_PATH + ".Controller": true, "test.Hotels": true}
+	uses := findFileMessageKeys(fset, file, "test", map[string]string{"revel": revel.REVEL_IMPORT_PATH}, controllerTypes)
+	actual := map[int][]string{}
+	for _, use := range uses {
+		actual[use.Line] = append(actual[use.Line], use.Key)
+	}
+	for line, keys := range expectedMessageKeys {
+		sort.Strings(keys)
+		sort.Strings(actual[line])
+		if !reflect.DeepEqual(actual[line], keys) {
+			t.Errorf("Line %d: expected keys %v, found %v", line, keys, actual[line])
+		}
+	}
+	if len(actual) != len(expectedMessageKeys) {
+		t.Errorf("Expected keys on %d lines, found %d: %v", len(expectedMessageKeys), len(actual), actual)
+	}
+}
+
+func TestTemplateMessagePattern(t *testing.T) {
+	var patternTests = []struct {
+		line string
+		keys []string
+	}{
+		{`<h1>{{msg . "hotels.title"}}</h1>`, []string{"hotels.title"}},
+		{`{{- msg $ "hotels.count" 3 -}}`, []string{"hotels.count"}},
+		{`{{.name | printf "%s" | msg . "hotels.piped"}}`, []string{"hotels.piped"}},
+		{`{{if eq (msg . "a") (msg . "b")}}`, []string{"a", "b"}},
+		{`&#123;&#123;msg . "escaped"&#125;&#125;`, nil},
+		{`{{msg . .key}}`, nil},
+	}
+	for _, test := range patternTests {
+		matches := templateMessagePattern.FindAllStringSubmatch(test.line, -1)
+		if len(matches) != len(test.keys) {
+			t.Errorf("%s: expected keys %v, got %v", test.line, test.keys, matches)
+			continue
+		}
+		for i, match := range matches {
+			if match[1] != test.keys[i] {
+				t.Errorf("%s: expected key %s, got %s", test.line, test.keys[i], match[1])
+			}
+		}
+	}
+}
+
+func TestFindTemplateMessageKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-views")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "Hotels"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "Hotels", "Show.html"), []byte("<h1>\n{{msg . \"hotels.title\"}}</h1>"), 0644)
+
+	uses := FindTemplateMessageKeys([]string{dir})
+	if len(uses) != 1 || uses[0].Key != "hotels.title" || uses[0].Line != 2 ||
+		uses[0].Path != filepath.Join(dir, "Hotels", "Show.html") {
+		t.Errorf("Unexpected message keys: %v", uses)
+	}
+}
@@ -82,10 +82,18 @@ type methodMap map[string][]*MethodSpec
 // Parse the app controllers directory and return a list of the controller types found.
 // Returns a CompileError if the parsing fails.
 func ProcessSource(roots []string) (*SourceInfo, *revel.Error) {
-	var (
-		srcInfo      *SourceInfo
-		compileError *revel.Error
-	)
+	var srcInfo *SourceInfo
+	compileError := walkPackages(roots, func(fset *token.FileSet, pkgImportPath, pkgPath string, pkg *ast.Package) {
+		srcInfo = appendSourceInfo(srcInfo, processPackage(fset, pkgImportPath, pkgPath, pkg))
+	})
+	return srcInfo, compileError
+}
+
+// Parse each package under the given roots (except main packages and app/tmp),
+// and call the given func with it.
+// Returns a CompileError if the parsing fails.
+func walkPackages(roots []string, processPackage func(fset *token.FileSet, pkgImportPath, pkgPath string, pkg *ast.Package)) *revel.Error {
+	var compileError *revel.Error
 
 	for _, root := range roots {
 		rootImportPath := importPathFromPath(root)
@@ -153,12 +161,12 @@ func ProcessSource(roots []string) (*SourceInfo, *revel.Error) {
 				pkg = v
 			}
 
-			srcInfo = appendSourceInfo(srcInfo, processPackage(fset, pkgImportPath, path, pkg))
+			processPackage(fset, pkgImportPath, path, pkg)
 			return nil
 		})
 	}
 
-	return srcInfo, compileError
+	return compileError
 }
 
 func appendSourceInfo(srcInfo1, srcInfo2 *SourceInfo) *SourceInfo {
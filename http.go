@@ -14,7 +14,7 @@ type Request struct {
 	ContentType     string
 	Format          string // "html", "xml", "json", or "text"
 	AcceptLanguages AcceptLanguages
-	Locale          string
+	Locale          string // The negotiated locale, set by the i18n plugin.
 }
 
 type Response struct {
@@ -124,6 +124,7 @@ func ResolveAcceptLanguage(req *http.Request) AcceptLanguages {
 		}
 	}
 
-	sort.Sort(acceptLanguages)
+	// Keep the order of the header for equal qualities.
+	sort.Stable(acceptLanguages)
 	return acceptLanguages
 }
@@ -3,10 +3,12 @@ package revel
 import (
 	"fmt"
 	"github.com/robfig/config"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 )
 
 const (
@@ -15,8 +17,10 @@ const (
 	messageFilesDirectory = "messages"
 	messageFilePattern    = `^\w+.[a-zA-Z]{2}$`
 	unknownValueFormat    = "??? %s ???"
-	defaultLanguageOption = "i18n.default_language"
-	localeCookieConfigKey = "i18n.cookie"
+	defaultLanguageOption  = "i18n.default_language"
+	fallbackLanguageOption = "i18n.fallback."
+	localeCookieConfigKey  = "i18n.cookie"
+	localeParamConfigKey   = "i18n.param"
 )
 
 var (
@@ -35,67 +39,211 @@ func MessageLanguages() []string {
 	return languages
 }
 
+// Named arguments of a message, for its {name} placeholders.
+type MessageArgs map[string]interface{}
+
 // Perform a message look-up for the given locale and message using the given arguments.
 //
 // When either an unknown locale or message is detected, a specially formatted string is returned.
+//
+// Arguments are formatted into the message with fmt.Sprintf, and named arguments
+// (given as MessageArgs) replace the {name} placeholders in it, which translators
+// may put in any order:
+//
+//   greeting=Hello {name}, you have {count} new messages
+//   Message("en", "greeting", MessageArgs{"name": "Rob", "count": 3})
+//
+// If there is a count (the "count" argument, or else the first number), the
+// message may have a form for each plural category of the language (zero, one,
+// two, few, many and other), as for CLDR:
+//
+//   hotels.count.one={count} hotel
+//   hotels.count.other={count} hotels
+//
+// The "other" form is used if there is none for the category, and the "zero"
+// form is used for 0 in any language that has it in its messages.
 func Message(locale, message string, args ...interface{}) string {
-	language, region := parseLocale(locale)
-	TRACE.Printf("Resolving message '%s' for language '%s' and region '%s'", message, language, region)
+	var named map[string]interface{}
+	var positional []interface{}
+	for _, arg := range args {
+		switch a := arg.(type) {
+		case MessageArgs:
+			named = a
+		case map[string]interface{}:
+			named = a
+		default:
+			positional = append(positional, arg)
+		}
+	}
 
-	messageConfig, knownLanguage := messages[language]
-	if !knownLanguage {
-		WARN.Printf("Unsupported language for locale '%s' and message '%s', trying default language", locale, message)
+	count := messageCount(named, positional)
+	value, found := pluralMessageValue(locale, message, count)
+	if !found {
+		value, found = messageValue(locale, message)
+	}
+	if !found {
+		return fmt.Sprintf(unknownValueFormat, message)
+	}
 
-		if defaultLanguage, found := Config.String(defaultLanguageOption); found {
-			TRACE.Printf("Using default language '%s'", defaultLanguage)
+	// Messages with placeholders only may be given the count as it is.
+	if named == nil && count != nil {
+		named = map[string]interface{}{"count": count}
+	}
 
-			messageConfig, knownLanguage = messages[defaultLanguage]
-			if !knownLanguage {
-				WARN.Printf("Unsupported default language for locale '%s' and message '%s'", defaultLanguage, message)
-				return fmt.Sprintf(unknownValueFormat, message)
+	if len(positional) > 0 && strings.Contains(value, "%") {
+		TRACE.Printf("Arguments detected, formatting '%s' with %v", value, positional)
+		value = fmt.Sprintf(value, positional...)
+	}
+	if named != nil {
+		value = messagePlaceholderPattern.ReplaceAllStringFunc(value, func(placeholder string) string {
+			if arg, ok := named[placeholder[1:len(placeholder)-1]]; ok {
+				return fmt.Sprint(arg)
 			}
-		} else {
-			WARN.Printf("Unable to find default language option (%s); messages for unsupported locales will never be translated", defaultLanguageOption)
-			return fmt.Sprintf(unknownValueFormat, message)
+			return placeholder
+		})
+	}
+
+	return value
+}
+
+// Matches the named placeholders of messages, e.g. {count}.
+var messagePlaceholderPattern = regexp.MustCompile(`\{\w+\}`)
+
+// Return the count of a message: the "count" named argument, or else the first
+// number given.
+func messageCount(named map[string]interface{}, positional []interface{}) interface{} {
+	if count, ok := named["count"]; ok {
+		return count
+	}
+	for _, arg := range positional {
+		if _, ok := toFloat(arg); ok {
+			return arg
 		}
 	}
+	return nil
+}
 
-	// This works because unlike the goconfig documentation suggests it will actually
-	// try to resolve message in DEFAULT if it did not find it in the given section.
-	value, error := messageConfig.String(region, message)
-	if error != nil {
+// Look up the plural form of the message for the count, e.g. hotels.count.few,
+// falling back to its "other" form.  The forms of a fallback language are only
+// used if the language of the locale has none.
+func pluralMessageValue(locale, message string, count interface{}) (value string, found bool) {
+	n, ok := toFloat(count)
+	if !ok {
+		return "", false
+	}
+	var forms []string
+	if n == 0 {
+		forms = append(forms, message+"."+PluralZero)
+	}
+	forms = append(forms, message+"."+PluralCategory(locale, count), message+"."+PluralOther)
+	return findFirstMessage(locale, forms...)
+}
+
+// Look up the raw (unformatted) message for the given locale.
+// Returns false if either the locale or the message is unknown.
+func messageValue(locale, message string) (value string, found bool) {
+	return findMessage(locale, message, true)
+}
+
+// Look up the raw message like messageValue, optionally warning about unknown
+// locales and messages.  (Some messages, like the format patterns, are optional)
+//
+// The message is looked up in the region of the locale, then its language, then
+// the fallback languages configured for that language, and then the default
+// language:
+//
+//   i18n.fallback.pt = es, fr
+//   i18n.default_language = en
+func findMessage(locale, message string, warn bool) (value string, found bool) {
+	if warn && !isMessageLanguage(locale) {
+		WARN.Printf("Unsupported language for locale '%s' and message '%s', trying fallback languages", locale, message)
+	}
+
+	if value, found = findFirstMessage(locale, message); !found && warn {
 		WARN.Printf("Unknown message '%s' for locale '%s'", message, locale)
-		return fmt.Sprintf(unknownValueFormat, message)
 	}
+	return
+}
+
+// Look up the first of the given messages that the language of the locale has,
+// or else that its first fallback language with any of them has.
+func findFirstMessage(locale string, messageNames ...string) (value string, found bool) {
+	language, region := parseLocale(locale)
+	TRACE.Printf("Resolving message '%s' for language '%s' and region '%s'", messageNames[0], language, region)
 
-	if len(args) > 0 {
-		TRACE.Printf("Arguments detected, formatting '%s' with %v", value, args)
-		value = fmt.Sprintf(value, args...)
+	for _, fallback := range messageLanguageChain(language) {
+		messageConfig, knownLanguage := messages[fallback]
+		if !knownLanguage {
+			continue
+		}
+
+		// This works because unlike the goconfig documentation suggests it will actually
+		// try to resolve message in DEFAULT if it did not find it in the given section.
+		section := ""
+		if fallback == language {
+			section = region
+		}
+		for _, message := range messageNames {
+			if value, error := messageConfig.String(section, message); error == nil {
+				if fallback != language {
+					TRACE.Printf("Using message '%s' of language '%s' for locale '%s'", message, fallback, locale)
+				}
+				return value, true
+			}
+		}
 	}
+	return "", false
+}
 
-	return value
+// Return the languages in which to look up messages for the given language: the
+// language itself, its configured fallbacks, and the default language.
+func messageLanguageChain(language string) []string {
+	chain := []string{language}
+	add := func(fallback string) {
+		fallback = strings.ToLower(strings.TrimSpace(fallback))
+		if fallback != "" && !ContainsString(chain, fallback) {
+			chain = append(chain, fallback)
+		}
+	}
+	if Config != nil {
+		for _, fallback := range strings.Split(Config.StringDefault(fallbackLanguageOption+language, ""), ",") {
+			add(fallback)
+		}
+		add(Config.StringDefault(defaultLanguageOption, ""))
+	}
+	return chain
 }
 
 func parseLocale(locale string) (language, region string) {
 	if strings.Contains(locale, "-") {
 		languageAndRegion := strings.Split(locale, "-")
-		return languageAndRegion[0], languageAndRegion[1]
+		return strings.ToLower(languageAndRegion[0]), strings.ToUpper(languageAndRegion[1])
 	}
 
-	return locale, ""
+	return strings.ToLower(locale), ""
 }
 
 // Recursively read and cache all available messages from all message files on the given path.
 func loadMessages(path string) {
-	messages = make(map[string]*config.Config)
-
-	if error := filepath.Walk(path, loadMessageFile); error != nil {
+	loaded, error := readMessages(path)
+	if error != nil {
 		ERROR.Println("Error reading messages files:", error)
 	}
+	messages = loaded
+}
+
+// Recursively read all messages from all message files on the given path, by
+// language.
+func readMessages(path string) (map[string]*config.Config, error) {
+	loaded := make(map[string]*config.Config)
+	error := WalkFiles(path, func(path string, info os.FileInfo, osError error) error {
+		return loadMessageFile(loaded, path, info, osError)
+	})
+	return loaded, error
 }
 
 // Load a single message file
-func loadMessageFile(path string, info os.FileInfo, osError error) error {
+func loadMessageFile(loaded map[string]*config.Config, path string, info os.FileInfo, osError error) error {
 	if osError != nil {
 		return osError
 	}
@@ -103,22 +251,18 @@ func loadMessageFile(path string, info os.FileInfo, osError error) error {
 		return nil
 	}
 
-	if matched, _ := regexp.MatchString(messageFilePattern, info.Name()); matched {
-		if config, error := parseMessagesFile(path); error != nil {
-			return error
+	if locale, config, error := ParseMessageFile(path); error != nil {
+		return error
+	} else if config != nil {
+		// If we have already parsed a message file for this locale, merge both
+		if _, exists := loaded[locale]; exists {
+			loaded[locale].Merge(config)
+			TRACE.Printf("Successfully merged messages for locale '%s'", locale)
 		} else {
-			locale := parseLocaleFromFileName(info.Name())
-
-			// If we have already parsed a message file for this locale, merge both
-			if _, exists := messages[locale]; exists {
-				messages[locale].Merge(config)
-				TRACE.Printf("Successfully merged messages for locale '%s'", locale)
-			} else {
-				messages[locale] = config
-			}
-
-			TRACE.Println("Successfully loaded messages from file", info.Name())
+			loaded[locale] = config
 		}
+
+		TRACE.Println("Successfully loaded messages from file", info.Name())
 	} else {
 		TRACE.Printf("Ignoring file %s because it did not have a valid extension", info.Name())
 	}
@@ -127,7 +271,7 @@ func loadMessageFile(path string, info os.FileInfo, osError error) error {
 }
 
 func parseMessagesFile(path string) (messageConfig *config.Config, error error) {
-	messageConfig, error = config.ReadDefault(path)
+	messageConfig, error = readConfigFile(path)
 	return
 }
 
@@ -141,11 +285,26 @@ type I18nPlugin struct {
 }
 
 func (p I18nPlugin) OnAppStart() {
-	loadMessages(filepath.Join(BasePath, messageFilesDirectory))
+	path := filepath.Join(BasePath, messageFilesDirectory)
+	loadMessages(path)
+	if MainWatcher != nil && Config.BoolDefault("watch.messages", true) && DirExists(path) {
+		MainWatcher.Listen(messageLoader{path}, path)
+	}
 }
 
 func (p I18nPlugin) BeforeRequest(c *Controller) {
-	if foundCookie, cookieValue := hasLocaleCookie(c.Request); foundCookie {
+	// The locale may have been given in the URL.  (See localeUrlConfigKey)
+	if c.Request.Locale != "" {
+		TRACE.Printf("Found locale in URL: %s", c.Request.Locale)
+		setCurrentLocaleControllerArguments(c, c.Request.Locale)
+		return
+	}
+
+	if foundParam, paramValue := hasLocaleParam(c.Request); foundParam {
+		TRACE.Printf("Found locale parameter value: %s", paramValue)
+		setLocaleCookie(c, paramValue)
+		setCurrentLocaleControllerArguments(c, paramValue)
+	} else if foundCookie, cookieValue := hasLocaleCookie(c.Request); foundCookie {
 		TRACE.Printf("Found locale cookie value: %s", cookieValue)
 		setCurrentLocaleControllerArguments(c, cookieValue)
 	} else if foundHeader, headerValue := hasAcceptLanguageHeader(c.Request); foundHeader {
@@ -163,18 +322,82 @@ func setCurrentLocaleControllerArguments(c *Controller, locale string) {
 	c.RenderArgs[CurrentLocaleRenderArg] = locale
 }
 
-// Determine whether the given request has valid Accept-Language value.
+// Determine whether the given request has valid Accept-Language value, and
+// return the most qualified locale whose language has messages.  If none does,
+// the most qualified locale is returned, to be translated by the fallbacks.
 //
 // Assumes that the accept languages stored in the request are sorted according to quality, with top
 // quality first in the slice.
 func hasAcceptLanguageHeader(request *Request) (bool, string) {
 	if request.AcceptLanguages != nil && len(request.AcceptLanguages) > 0 {
+		if locale, found := negotiateLocale(request.AcceptLanguages); found {
+			return true, locale
+		}
 		return true, request.AcceptLanguages[0].Language
 	}
 
 	return false, ""
 }
 
+// Return the most qualified of the accepted locales whose language has
+// messages.  A wildcard accepts the default language.
+func negotiateLocale(acceptLanguages AcceptLanguages) (string, bool) {
+	for _, acceptLanguage := range acceptLanguages {
+		if acceptLanguage.Quality <= 0 {
+			continue
+		}
+		locale := strings.TrimSpace(acceptLanguage.Language)
+		if locale == "*" && Config != nil {
+			locale = Config.StringDefault(defaultLanguageOption, "")
+		}
+		if isMessageLanguage(locale) {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// Return true if there are messages for the language of the locale.
+func isMessageLanguage(locale string) bool {
+	language, _ := parseLocale(locale)
+	_, found := messages[language]
+	return found
+}
+
+// Determine whether the given request has the locale parameter (as named by
+// i18n.param, e.g. ?lang=nl) with a locale of a language that has messages.
+func hasLocaleParam(request *Request) (bool, string) {
+	if Config == nil {
+		return false, ""
+	}
+	name := Config.StringDefault(localeParamConfigKey, "")
+	if name == "" || request == nil || request.URL == nil {
+		return false, ""
+	}
+	locale := request.URL.Query().Get(name)
+	if locale == "" {
+		return false, ""
+	}
+	if !isMessageLanguage(locale) {
+		TRACE.Printf("Ignoring locale parameter value '%s' of an unsupported language", locale)
+		return false, ""
+	}
+	return true, locale
+}
+
+// Save the locale to the locale cookie, for the following requests.
+func setLocaleCookie(c *Controller, locale string) {
+	if c.Response == nil || c.Response.Out == nil {
+		return
+	}
+	http.SetCookie(c.Response.Out, &http.Cookie{
+		Name:    Config.StringDefault(localeCookieConfigKey, CookiePrefix+"_LANG"),
+		Value:   locale,
+		Path:    "/",
+		Expires: time.Now().AddDate(1, 0, 0),
+	})
+}
+
 // Determine whether the given request has a valid language cookie value.
 func hasLocaleCookie(request *Request) (bool, string) {
 	if request != nil && request.Cookies() != nil {
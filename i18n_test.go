@@ -4,7 +4,9 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -81,6 +83,145 @@ func TestI18nMessageWithDefaultLocale(t *testing.T) {
 	}
 }
 
+func TestI18nPluralsAndNamedArguments(t *testing.T) {
+	loadMessages(testDataPath)
+	loadTestI18nConfig(t)
+
+	var messageTests = []struct {
+		locale, message string
+		args            []interface{}
+		expected        string
+	}{
+		{"en", "hotels.count", []interface{}{0}, "No hotels"},
+		{"en", "hotels.count", []interface{}{1}, "1 hotel"},
+		{"en", "hotels.count", []interface{}{5}, "5 hotels"},
+		{"en", "hotels.count", []interface{}{MessageArgs{"count": 1.5}}, "1.5 hotels"},
+		// Dutch has no zero form.
+		{"nl", "hotels.count", []interface{}{0}, "0 hotels"},
+		{"nl", "hotels.count", []interface{}{1}, "1 hotel"},
+		// Positional arguments still work, and the first number counts.
+		{"en", "hotels.found", []interface{}{3, "Paris"}, "Found 3 hotels in Paris"},
+		// Named arguments, in the order of each language.
+		{"en", "hotels.booked", []interface{}{MessageArgs{"name": "Rob", "count": 2, "hotel": "Ritz"}}, "Rob booked 2 nights at Ritz"},
+		{"nl", "hotels.booked", []interface{}{map[string]interface{}{"name": "Rob", "count": 2, "hotel": "Ritz"}}, "Ritz: 2 nachten geboekt door Rob"},
+		// Unknown placeholders are left as they are.
+		{"en", "hotels.booked", []interface{}{MessageArgs{"name": "Rob"}}, "Rob booked {count} nights at {hotel}"},
+	}
+	for _, test := range messageTests {
+		if actual := Message(test.locale, test.message, test.args...); actual != test.expected {
+			t.Errorf("%s %s %v: expected '%s', got '%s'", test.locale, test.message, test.args, test.expected, actual)
+		}
+	}
+}
+
+func TestI18nMessageFallbacks(t *testing.T) {
+	loadMessages(testDataPath)
+	loadTestI18nConfig(t)
+
+	// Messages missing for a language are taken from the default language.
+	if message := Message("nl", "arguments.string", "Vincent Hanna"); message != "My name is Vincent Hanna" {
+		t.Errorf("Expected the default language message, got '%s'", message)
+	}
+	// Unsupported languages use their configured fallbacks first.
+	if message := Message("fr", "greeting"); message != "Hallo" {
+		t.Errorf("Expected the fallback language message, got '%s'", message)
+	}
+	if message := Message("fr", "only_exists_in_default"); message != "Default" {
+		t.Errorf("Expected the default language message, got '%s'", message)
+	}
+	// Regions are matched regardless of case.
+	if message := Message("nl-be", "greeting"); message != "Hallokes" {
+		t.Errorf("Expected the region message, got '%s'", message)
+	}
+}
+
+func TestNegotiateLocale(t *testing.T) {
+	loadMessages(testDataPath)
+	loadTestI18nConfig(t)
+
+	var negotiationTests = []struct {
+		acceptLanguages []string
+		expected        string
+	}{
+		// The first supported language, by quality.
+		{[]string{"fr", "nl-BE", "en"}, "nl-BE"},
+		{[]string{"de", "en-GB"}, "en-GB"},
+		// Without a supported language, the most qualified one.
+		{[]string{"fr", "de"}, "fr"},
+		{[]string{"fr", "*"}, "en"},
+	}
+	for _, test := range negotiationTests {
+		if _, locale := hasAcceptLanguageHeader(buildRequestWithAcceptLanguages(test.acceptLanguages...)); locale != test.expected {
+			t.Errorf("%v: expected locale '%s', got '%s'", test.acceptLanguages, test.expected, locale)
+		}
+	}
+
+	httpRequest, _ := http.NewRequest("GET", "/", nil)
+	httpRequest.Header.Set("Accept-Language", "fr;q=0.9, nl;q=0, en;q=0.5")
+	if _, locale := hasAcceptLanguageHeader(NewRequest(httpRequest)); locale != "en" {
+		t.Errorf("Expected locale 'en', ignoring the unacceptable 'nl', got '%s'", locale)
+	}
+}
+
+func TestLocaleParam(t *testing.T) {
+	loadMessages(testDataPath)
+	loadTestI18nConfig(t)
+	plugin := I18nPlugin{}
+
+	httpRequest, _ := http.NewRequest("GET", "/?lang=nl", nil)
+	httpRequest.AddCookie(&http.Cookie{Name: "APP_LANG", Value: "en"})
+	recorder := httptest.NewRecorder()
+	controller := NewController(NewRequest(httpRequest), NewResponse(recorder), &ControllerType{reflect.TypeOf(Controller{}), nil})
+	if plugin.BeforeRequest(controller); controller.Request.Locale != "nl" {
+		t.Errorf("Expected the locale parameter to override the cookie, found '%s'", controller.Request.Locale)
+	}
+	if cookie := recorder.HeaderMap.Get("Set-Cookie"); !strings.HasPrefix(cookie, "APP_LANG=nl;") {
+		t.Errorf("Expected the locale to be saved to the cookie, got '%s'", cookie)
+	}
+
+	// Unsupported languages are ignored.
+	httpRequest, _ = http.NewRequest("GET", "/?lang=xx", nil)
+	recorder = httptest.NewRecorder()
+	controller = NewController(NewRequest(httpRequest), NewResponse(recorder), &ControllerType{reflect.TypeOf(Controller{}), nil})
+	if plugin.BeforeRequest(controller); controller.Request.Locale != "" {
+		t.Errorf("Expected the unsupported locale parameter to be ignored, found '%s'", controller.Request.Locale)
+	}
+	if cookie := recorder.HeaderMap.Get("Set-Cookie"); cookie != "" {
+		t.Errorf("Expected no cookie, got '%s'", cookie)
+	}
+}
+
+func TestPluralCategory(t *testing.T) {
+	var categoryTests = []struct {
+		locale   string
+		count    interface{}
+		expected string
+	}{
+		{"en", 1, PluralOne},
+		{"en", 0, PluralOther},
+		{"en-US", 1.5, PluralOther},
+		{"fr", 0, PluralOne},
+		{"fr", 2, PluralOther},
+		{"ja", 1, PluralOther},
+		{"ru", 21, PluralOne},
+		{"ru", 3, PluralFew},
+		{"ru", 11, PluralMany},
+		{"pl", 22, PluralFew},
+		{"pl", 25, PluralMany},
+		{"cs", 4, PluralFew},
+		{"ar", 0, PluralZero},
+		{"ar", 2, PluralTwo},
+		{"ar", 103, PluralFew},
+		{"ar", 111, PluralMany},
+		{"en", "not a number", PluralOther},
+	}
+	for _, test := range categoryTests {
+		if actual := PluralCategory(test.locale, test.count); actual != test.expected {
+			t.Errorf("%s %v: expected %s, got %s", test.locale, test.count, test.expected, actual)
+		}
+	}
+}
+
 func TestHasLocaleCookie(t *testing.T) {
 	loadTestI18nConfig(t)
 
@@ -24,4 +24,6 @@ func init() {
 	RegisterPlugin(ValidationPlugin{})
 	RegisterPlugin(InterceptorPlugin{})
 	RegisterPlugin(I18nPlugin{})
+	RegisterPlugin(CompressionPlugin{})
+	RegisterPlugin(ETagPlugin{})
 }
@@ -0,0 +1,183 @@
+package revel
+
+import (
+	"regexp"
+	"strings"
+	"text/template/parse"
+)
+
+// With locale URLs turned on in app.conf, URLs may begin with a locale, which
+// sets the locale of the request and is stripped before routing:
+//
+//   i18n.url_prefix = true
+//
+//   /fr/hotels/12  =>  GET /hotels/12, with Request.Locale "fr"
+//
+// Only locales of languages that have messages are stripped.  URLs reversed in
+// templates (e.g. {{url "Hotels.Show" 12}}) and redirects to actions begin with
+// the current locale, and localeUrl gives the URL of the current page in
+// another locale, for language switchers:
+//
+//   {{range $locale, $url := localeUrls .}}<a href="{{$url}}">{{$locale}}</a>{{end}}
+const localeUrlConfigKey = "i18n.url_prefix"
+
+// Matches a path segment that looks like a locale, e.g. "fr" or "pt-BR".
+var localeSegmentPattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,4})?$`)
+
+// Return true if locale URLs are turned on.
+func localeUrlsEnabled() bool {
+	return Config != nil && Config.BoolDefault(localeUrlConfigKey, false)
+}
+
+// Split the locale off the beginning of the path, if it has one.
+//   "/fr/hotels/12" => "fr", "/hotels/12"
+func splitLocalePath(path string) (locale, rest string) {
+	segment := strings.TrimPrefix(path, "/")
+	rest = "/"
+	if slash := strings.Index(segment, "/"); slash != -1 {
+		segment, rest = segment[:slash], segment[slash:]
+	}
+	if !localeSegmentPattern.MatchString(segment) || !isMessageLanguage(segment) {
+		return "", path
+	}
+	return segment, rest
+}
+
+// Return the URL beginning with the locale, if locale URLs are turned on.
+func localizeUrl(locale, url string) string {
+	if locale == "" || !localeUrlsEnabled() || !strings.HasPrefix(url, "/") {
+		return url
+	}
+	return "/" + locale + url
+}
+
+// Return the URL of the request in the given locale: its path (without the
+// locale it may have had) with the locale, and its query.
+func LocaleUrl(req *Request, locale string) string {
+	path := req.URL.Path
+	if localeUrlsEnabled() {
+		// (The router has stripped the locale already, but a request that was
+		// not routed may still have it)
+		_, path = splitLocalePath(path)
+		path = "/" + locale + path
+	}
+
+	query := req.URL.Query()
+	if Config != nil {
+		if param := Config.StringDefault(localeParamConfigKey, ""); param != "" {
+			if localeUrlsEnabled() {
+				query.Del(param)
+			} else {
+				query.Set(param, locale)
+			}
+		}
+	}
+	if len(query) > 0 {
+		return path + "?" + query.Encode()
+	}
+	return path
+}
+
+// Return the URL of the request in each of the message languages.
+func LocaleUrls(req *Request) map[string]string {
+	urls := map[string]string{}
+	for _, language := range MessageLanguages() {
+		urls[language] = LocaleUrl(req, language)
+	}
+	return urls
+}
+
+// Return the request of the render args' controller, or nil.
+func renderArgsRequest(renderArgs map[string]interface{}) *Request {
+	if c, ok := renderArgs["Controller"].(*Controller); ok && c.Request != nil && c.Request.Request != nil {
+		return c.Request
+	}
+	return nil
+}
+
+// Return the current locale, from the render args of the page given to url
+// before the action (see localizeCalls), and the action and its args.
+func splitUrlRenderArgs(args []interface{}) (locale string, rest []interface{}) {
+	for len(args) > 0 {
+		if _, isAction := args[0].(string); isAction {
+			break
+		}
+		if renderArgs, ok := args[0].(map[string]interface{}); ok && locale == "" {
+			locale, _ = renderArgs[CurrentLocaleRenderArg].(string)
+		}
+		args = args[1:]
+	}
+	return locale, args
+}
+
+// Matches the templates defined in a template source, e.g. {{define "content"}}
+var definedTemplatePattern = regexp.MustCompile(`\{\{-?\s*(?:define|block)\s+"([^"]+)"`)
+
+// Return the names of the templates parsed from the source: its own, and the
+// ones it defines.
+func parsedTemplateNames(name, source string) []string {
+	names := []string{name}
+	for _, match := range definedTemplatePattern.FindAllStringSubmatch(source, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// The template funcs given the render args of the page, for its locale.
+var localizedFuncs = map[string]bool{"url": true, "date": true, "datetime": true}
+
+// Give the render args of the page ($) to the calls of url, date and datetime
+// in the parse tree, so that the URLs begin with the current locale and dates
+// are in its format, even within range and with:
+//   {{url "Hotels.Show" .Id}} => {{url $ "Hotels.Show" .Id}}
+// ($ of a template invoked with other data is that data, so templates with
+// URLs are invoked with the render args: {{template "links.html" $}})
+func localizeCalls(node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node != nil {
+			for _, child := range node.Nodes {
+				localizeCalls(child)
+			}
+		}
+	case *parse.ActionNode:
+		localizeCalls(node.Pipe)
+	case *parse.TemplateNode:
+		localizeCalls(node.Pipe)
+	case *parse.IfNode:
+		localizeBranchCalls(&node.BranchNode)
+	case *parse.RangeNode:
+		localizeBranchCalls(&node.BranchNode)
+	case *parse.WithNode:
+		localizeBranchCalls(&node.BranchNode)
+	case *parse.PipeNode:
+		if node != nil {
+			for _, cmd := range node.Cmds {
+				localizeCalls(cmd)
+			}
+		}
+	case *parse.CommandNode:
+		if ident, ok := node.Args[0].(*parse.IdentifierNode); ok && localizedFuncs[ident.Ident] && !isPageVariable(node.Args[1:]) {
+			page := &parse.VariableNode{NodeType: parse.NodeVariable, Pos: ident.Pos, Ident: []string{"$"}}
+			node.Args = append([]parse.Node{ident, page}, node.Args[1:]...)
+		}
+		for _, arg := range node.Args {
+			localizeCalls(arg)
+		}
+	}
+}
+
+func localizeBranchCalls(branch *parse.BranchNode) {
+	localizeCalls(branch.Pipe)
+	localizeCalls(branch.List)
+	localizeCalls(branch.ElseList)
+}
+
+// Return true if the first of the args is $, so that the call was localized.
+func isPageVariable(args []parse.Node) bool {
+	if len(args) == 0 {
+		return false
+	}
+	variable, ok := args[0].(*parse.VariableNode)
+	return ok && len(variable.Ident) == 1 && variable.Ident[0] == "$"
+}
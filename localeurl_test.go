@@ -0,0 +1,118 @@
+package revel
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func loadTestLocaleUrlConfig(t *testing.T) {
+	loadMessages(testDataPath)
+	loadTestI18nConfig(t)
+	Config.SetOption(localeUrlConfigKey, "true")
+}
+
+func TestSplitLocalePath(t *testing.T) {
+	loadTestLocaleUrlConfig(t)
+
+	var splitTests = []struct {
+		path, locale, rest string
+	}{
+		{"/nl/hotels/12", "nl", "/hotels/12"},
+		{"/en-GB/hotels", "en-GB", "/hotels"},
+		{"/nl", "nl", "/"},
+		// Only the languages with messages are locales.
+		{"/fr/hotels", "", "/fr/hotels"},
+		{"/hotels/12", "", "/hotels/12"},
+		{"/", "", "/"},
+	}
+	for _, test := range splitTests {
+		locale, rest := splitLocalePath(test.path)
+		if locale != test.locale || rest != test.rest {
+			t.Errorf("%s: expected %q %q, got %q %q", test.path, test.locale, test.rest, locale, rest)
+		}
+	}
+}
+
+func TestLocalizeUrl(t *testing.T) {
+	loadTestLocaleUrlConfig(t)
+
+	if url := localizeUrl("nl", "/hotels/12"); url != "/nl/hotels/12" {
+		t.Errorf("Expected /nl/hotels/12, got %s", url)
+	}
+	if url := localizeUrl("", "/hotels/12"); url != "/hotels/12" {
+		t.Errorf("Expected /hotels/12 without a locale, got %s", url)
+	}
+
+	Config.SetOption(localeUrlConfigKey, "false")
+	if url := localizeUrl("nl", "/hotels/12"); url != "/hotels/12" {
+		t.Errorf("Expected /hotels/12 with locale URLs turned off, got %s", url)
+	}
+}
+
+func TestLocaleUrls(t *testing.T) {
+	loadTestLocaleUrlConfig(t)
+
+	httpRequest, _ := http.NewRequest("GET", "/nl/hotels?page=2&lang=nl", nil)
+	request := NewRequest(httpRequest)
+	expected := map[string]string{
+		"en": "/en/hotels?page=2",
+		"nl": "/nl/hotels?page=2",
+	}
+	if urls := LocaleUrls(request); !reflect.DeepEqual(urls, expected) {
+		t.Errorf("Expected %v, got %v", expected, urls)
+	}
+
+	// Without locale URLs, the locale parameter switches the locale.
+	Config.SetOption(localeUrlConfigKey, "false")
+	httpRequest, _ = http.NewRequest("GET", "/hotels?page=2", nil)
+	if url := LocaleUrl(NewRequest(httpRequest), "nl"); url != "/hotels?lang=nl&page=2" {
+		t.Errorf("Expected /hotels?lang=nl&page=2, got %s", url)
+	}
+}
+
+func TestBeforeRequestWithUrlLocale(t *testing.T) {
+	loadTestLocaleUrlConfig(t)
+
+	request := buildRequestWithCookie("APP_LANG", "en")
+	request.Locale = "nl"
+	controller := NewController(request, nil, &ControllerType{reflect.TypeOf(Controller{}), nil})
+	if (I18nPlugin{}).BeforeRequest(controller); controller.RenderArgs[CurrentLocaleRenderArg] != "nl" {
+		t.Errorf("Expected the locale of the URL to win, found '%v'", controller.RenderArgs[CurrentLocaleRenderArg])
+	}
+}
+
+func TestUrlCallsGetTheRenderArgs(t *testing.T) {
+	defer func(url interface{}) { TemplateFuncs["url"] = url }(TemplateFuncs["url"])
+	TemplateFuncs["url"] = func(args ...interface{}) string {
+		locale, args := splitUrlRenderArgs(args)
+		return fmt.Sprint(locale, args)
+	}
+
+	dir, err := ioutil.TempDir("", "revel-views")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := `{{url "A.B" 1}} {{range .ids}}{{url "A.C" .}}{{end}} {{url . "A.D"}} {{url $ "A.E"}} ` +
+		`{{"A.F" | url}} {{with .ids}}{{template "link" .}}{{end}}{{define "link"}}{{url "A.G"}}{{end}}`
+	ioutil.WriteFile(filepath.Join(dir, "Page.html"), []byte(source), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "Page.txt"), []byte(`{{if true}}{{url "A.B"}}{{end}}`), 0644)
+
+	loader := NewTemplateLoader([]string{dir})
+	if err := loader.Refresh(); err != nil {
+		t.Fatalf("Failed to load templates: %s", err)
+	}
+	args := map[string]interface{}{CurrentLocaleRenderArg: "nl", "ids": []int{1, 2}}
+	if actual, expected := renderTestTemplate(t, loader, "Page.html", args),
+		"nl[A.B 1] nl[A.C 1]nl[A.C 2] nl[A.D] nl[A.E] nl[A.F] [A.G]"; actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+	if actual := renderTestTemplate(t, loader, "Page.txt", args); actual != "nl[A.B]" {
+		t.Errorf("Expected the url of the text template to be localized, got %s", actual)
+	}
+}
@@ -0,0 +1,473 @@
+package revel
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/robfig/config"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Besides goconfig files named by language (e.g. messages/hotels.fr), the
+// messages may be in gettext PO files or JSON catalogs, named by locale and
+// extension:
+//
+//   messages/hotels.fr.po
+//   messages/hotels.pt-BR.json   (messages for the BR region of pt)
+//
+// The messages of a language are merged, whatever the format of their files.
+//
+// In PO files, msgid is the message key, and a msgctxt is prepended to it:
+//
+//   msgctxt "button"
+//   msgid "save"          =>  button.save
+//
+// The msgstr[i] of plural messages are the plural forms of the Plural-Forms
+// header, each of which is given the CLDR category of most of the numbers it is
+// used for.  (Files whose forms do not match the CLDR rules of the language are
+// rejected)  Without the header, they are the plural forms the language uses,
+// in the CLDR order (zero, one, two, few, many, other), e.g. one and other for
+// French:
+//
+//   msgid "hotels.count"
+//   msgid_plural "hotels.count"
+//   msgstr[0] "{count} hôtel"     =>  hotels.count.one
+//   msgstr[1] "{count} hôtels"    =>  hotels.count.other
+//
+// Fuzzy and untranslated messages are skipped.
+//
+// JSON catalogs have nested objects, whose keys are joined by dots:
+//
+//   {"hotels": {"count": {"one": "{count} hotel", "other": "{count} hotels"}}}
+var messageFileLoaders = map[string]func(path, language string) (map[string]string, error){
+	".po":   parsePoFile,
+	".json": parseJsonMessagesFile,
+}
+
+// Matches the name of PO and JSON message files, e.g. hotels.pt-BR.json
+var catalogFilePattern = regexp.MustCompile(`^(\w+\.)?([a-zA-Z]{2}(-[a-zA-Z]{2})?)\.\w+$`)
+
+// Return true if the file name is that of a message file, in any format.
+func isMessageFile(name string) bool {
+	if _, found := messageFileLoaders[filepath.Ext(name)]; found {
+		return catalogFilePattern.MatchString(name)
+	}
+	matched, _ := regexp.MatchString(messageFilePattern, name)
+	return matched
+}
+
+// Parse the message file at the given path, in any format.  Returns the
+// language it has messages for, or "" if it is not a message file.
+func ParseMessageFile(path string) (language string, messageConfig *config.Config, err error) {
+	name := filepath.Base(path)
+	if !isMessageFile(name) {
+		return "", nil, nil
+	}
+
+	loader, found := messageFileLoaders[filepath.Ext(name)]
+	if !found {
+		messageConfig, err = parseMessagesFile(path)
+		return parseLocaleFromFileName(name), messageConfig, err
+	}
+
+	language, region := parseLocale(catalogFilePattern.FindStringSubmatch(name)[2])
+	values, err := loader(path, language)
+	if err != nil {
+		return "", nil, err
+	}
+	messageConfig = config.NewDefault()
+	section := config.DEFAULT_SECTION
+	if region != "" {
+		section = region
+	}
+	for key, value := range values {
+		messageConfig.AddOption(section, key, value)
+	}
+	return language, messageConfig, nil
+}
+
+// Matches the keyword lines of PO files, e.g. msgstr[1] "{count} hotels"
+var poKeywordPattern = regexp.MustCompile(`^(msgctxt|msgid_plural|msgid|msgstr(?:\[(\d+)\])?)\s+(".*")$`)
+
+// An entry of a PO file.
+type poEntry struct {
+	context, id, idPlural string
+	strs                  map[int]*string // msgstr, or msgstr[i] of plural messages.
+	fuzzy                 bool
+}
+
+// Read the messages of a gettext PO file.
+func parsePoFile(path, language string) (map[string]string, error) {
+	fileBytes, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		values     = map[string]string{}
+		categories = integerPluralCategories(language)
+		entry      = &poEntry{strs: map[int]*string{}}
+		current    *string // The string that continuation lines add to.
+		headerErr  error
+	)
+	flush := func() {
+		if header, found := entry.strs[0]; entry.id == "" && found {
+			// The header, which may give the plural forms of the file.
+			if match := poPluralFormsPattern.FindStringSubmatch(*header); match != nil {
+				categories, headerErr = poPluralCategories(match[1], match[2], language)
+			}
+		}
+		if entry.id != "" && !entry.fuzzy {
+			key := entry.id
+			if entry.context != "" {
+				key = entry.context + "." + key
+			}
+			for i, str := range entry.strs {
+				switch {
+				case *str == "":
+				case entry.idPlural == "":
+					values[key] = *str
+				case i < len(categories):
+					values[key+"."+categories[i]] = *str
+				}
+			}
+		}
+		entry, current = &poEntry{strs: map[int]*string{}}, nil
+	}
+
+	for i, line := range strings.Split(string(fileBytes), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(entry.strs) > 0 {
+				flush()
+			}
+			current = nil
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if len(entry.strs) > 0 {
+				flush()
+			}
+			if strings.HasPrefix(line, "#,") && strings.Contains(line, "fuzzy") {
+				entry.fuzzy = true
+			}
+			continue
+		}
+
+		quoted := line
+		if !strings.HasPrefix(line, `"`) {
+			match := poKeywordPattern.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("%s:%d: syntax error", path, i+1)
+			}
+			keyword := match[1]
+			quoted = match[3]
+
+			// (Entries are usually, but not always, separated by blank lines or
+			// comments)
+			if len(entry.strs) > 0 && (keyword == "msgctxt" || keyword == "msgid") {
+				flush()
+			}
+			switch keyword {
+			case "msgctxt":
+				current = &entry.context
+			case "msgid":
+				current = &entry.id
+			case "msgid_plural":
+				current = &entry.idPlural
+			default:
+				index, _ := strconv.Atoi(match[2])
+				current = new(string)
+				entry.strs[index] = current
+			}
+		} else if current == nil {
+			return nil, fmt.Errorf("%s:%d: unexpected string", path, i+1)
+		}
+
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid string %s", path, i+1, quoted)
+		}
+		*current += value
+	}
+	flush()
+	if headerErr != nil {
+		return nil, fmt.Errorf("%s: %s", path, headerErr)
+	}
+	return values, nil
+}
+
+// Matches the Plural-Forms header of PO files, e.g.
+// Plural-Forms: nplurals=2; plural=(n != 1);
+var poPluralFormsPattern = regexp.MustCompile(`(?m)^Plural-Forms:\s*nplurals\s*=\s*(\d+)\s*;\s*plural\s*=\s*([^;\n]+)`)
+
+// Return the plural category of each plural form (msgstr[i]) of a PO file:
+// the category of most of the numbers that get the form, according to the
+// plural expression.  E.g. for Latvian,
+//   nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n != 0 ? 1 : 2)
+// gives one, other and zero.
+func poPluralCategories(nplurals, expr, language string) ([]string, error) {
+	plural, err := parsePluralExpr(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Plural-Forms %q: %s", expr, err)
+	}
+	count, _ := strconv.Atoi(nplurals)
+	categoryCounts := make([]map[string]int, count)
+	for i := range categoryCounts {
+		categoryCounts[i] = map[string]int{}
+	}
+	for n := 0; n <= 200; n++ {
+		if form := plural(n); form >= 0 && form < count {
+			categoryCounts[form][PluralCategory(language, n)]++
+		}
+	}
+
+	categories := make([]string, count)
+	used := map[string]bool{}
+	for form, counts := range categoryCounts {
+		for _, category := range []string{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther} {
+			if counts[category] > counts[categories[form]] {
+				categories[form] = category
+			}
+		}
+		if categories[form] == "" || used[categories[form]] {
+			return nil, fmt.Errorf("the Plural-Forms %q do not match the plural rules of %s", expr, language)
+		}
+		used[categories[form]] = true
+	}
+	return categories, nil
+}
+
+// Parse the C expression of the gettext plural forms, e.g.
+// "(n%10==1 && n%100!=11 ? 0 : 1)", into a func returning the form for n.
+func parsePluralExpr(expr string) (func(n int) int, error) {
+	p := &pluralExprParser{expr: strings.Replace(expr, " ", "", -1)}
+	plural := p.ternary()
+	if p.err == nil && p.pos < len(p.expr) {
+		p.fail("unexpected %q", p.expr[p.pos:])
+	}
+	return plural, p.err
+}
+
+type pluralExprParser struct {
+	expr string
+	pos  int
+	err  error
+}
+
+// The binary operators, by precedence, from the lowest.
+var pluralExprOperators = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *pluralExprParser) fail(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (p *pluralExprParser) accept(token string) bool {
+	if strings.HasPrefix(p.expr[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+// ternary := binary [ "?" ternary ":" ternary ]
+func (p *pluralExprParser) ternary() func(n int) int {
+	cond := p.binary(0)
+	if !p.accept("?") {
+		return cond
+	}
+	then := p.ternary()
+	if !p.accept(":") {
+		p.fail("expected ':' at %d", p.pos)
+	}
+	otherwise := p.ternary()
+	return func(n int) int {
+		if cond(n) != 0 {
+			return then(n)
+		}
+		return otherwise(n)
+	}
+}
+
+// binary := operand { operator operand }, for the operators of the level and
+// higher ones.
+func (p *pluralExprParser) binary(level int) func(n int) int {
+	if level == len(pluralExprOperators) {
+		return p.unary()
+	}
+	left := p.binary(level + 1)
+	for {
+		var op string
+		for _, candidate := range pluralExprOperators[level] {
+			if p.accept(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left
+		}
+		left = pluralExprOperation(op, left, p.binary(level+1))
+	}
+}
+
+// unary := "!" unary | "n" | number | "(" ternary ")"
+func (p *pluralExprParser) unary() func(n int) int {
+	switch {
+	case p.accept("!"):
+		operand := p.unary()
+		return func(n int) int { return boolInt(operand(n) == 0) }
+	case p.accept("n"):
+		return func(n int) int { return n }
+	case p.accept("("):
+		inner := p.ternary()
+		if !p.accept(")") {
+			p.fail("expected ')' at %d", p.pos)
+		}
+		return inner
+	}
+	start := p.pos
+	for p.pos < len(p.expr) && p.expr[p.pos] >= '0' && p.expr[p.pos] <= '9' {
+		p.pos++
+	}
+	value, err := strconv.Atoi(p.expr[start:p.pos])
+	if err != nil {
+		p.fail("expected a number at %d", start)
+	}
+	return func(n int) int { return value }
+}
+
+func pluralExprOperation(op string, left, right func(n int) int) func(n int) int {
+	return func(n int) int {
+		a, b := left(n), right(n)
+		switch op {
+		case "||":
+			return boolInt(a != 0 || b != 0)
+		case "&&":
+			return boolInt(a != 0 && b != 0)
+		case "==":
+			return boolInt(a == b)
+		case "!=":
+			return boolInt(a != b)
+		case "<=":
+			return boolInt(a <= b)
+		case ">=":
+			return boolInt(a >= b)
+		case "<":
+			return boolInt(a < b)
+		case ">":
+			return boolInt(a > b)
+		case "+":
+			return a + b
+		case "-":
+			return a - b
+		case "*":
+			return a * b
+		}
+		if b == 0 {
+			return 0
+		}
+		if op == "/" {
+			return a / b
+		}
+		return a % b
+	}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Return the plural categories that the language uses for integers, in the
+// CLDR order, as the plural forms of gettext are.
+func integerPluralCategories(language string) (categories []string) {
+	used := map[string]bool{}
+	for n := 0; n <= 200; n++ {
+		used[PluralCategory(language, n)] = true
+	}
+	for _, category := range []string{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther} {
+		if used[category] {
+			categories = append(categories, category)
+		}
+	}
+	return
+}
+
+// Read the messages of a JSON catalog.
+func parseJsonMessagesFile(path, language string) (map[string]string, error) {
+	fileBytes, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var catalog map[string]interface{}
+	if err = json.Unmarshal(fileBytes, &catalog); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	values := map[string]string{}
+	if err = flattenCatalog(values, "", catalog); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return values, nil
+}
+
+// Add the messages of the catalog to values, with their keys joined by dots.
+func flattenCatalog(values map[string]string, prefix string, catalog map[string]interface{}) error {
+	for key, value := range catalog {
+		switch value := value.(type) {
+		case string:
+			values[prefix+key] = value
+		case float64:
+			values[prefix+key] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			values[prefix+key] = strconv.FormatBool(value)
+		case map[string]interface{}:
+			if err := flattenCatalog(values, prefix+key+".", value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported value for message %s: %v", prefix+key, value)
+		}
+	}
+	return nil
+}
+
+// The messageLoader reloads the messages when their files change.
+type messageLoader struct {
+	path string
+}
+
+func (loader messageLoader) Refresh() *Error {
+	loaded, err := readMessages(loader.path)
+	if err != nil {
+		return &Error{
+			Title:       "Messages Error",
+			Path:        loader.path,
+			Description: err.Error(),
+		}
+	}
+	messages = loaded
+	return nil
+}
+
+func (loader messageLoader) WatchDir(info os.FileInfo) bool {
+	return !strings.HasPrefix(info.Name(), ".")
+}
+
+func (loader messageLoader) WatchFile(basename string) bool {
+	return isMessageFile(filepath.Base(basename))
+}
@@ -0,0 +1,210 @@
+package revel
+
+import (
+	"github.com/robfig/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testCatalogsPath = "testdata/catalogs"
+
+func TestIsMessageFile(t *testing.T) {
+	var fileNameTests = []struct {
+		name    string
+		message bool
+	}{
+		{"app.de", true},
+		{"app.de.po", true},
+		{"de.po", true},
+		{"app.pt-BR.json", true},
+		{"app.po", false},
+		{"app.json", false},
+		{"app.txt", false},
+		{"app.deu.json", false},
+	}
+	for _, test := range fileNameTests {
+		if isMessageFile(test.name) != test.message {
+			t.Errorf("%s: expected message file %v", test.name, test.message)
+		}
+	}
+}
+
+func TestPoMessages(t *testing.T) {
+	loadMessages(testCatalogsPath)
+
+	var messageTests = []struct {
+		locale, key string
+		args        []interface{}
+		expected    string
+	}{
+		{"de", "button.save", nil, "Speichern"},
+		{"de", "hotels.count", []interface{}{1}, "1 Hotel"},
+		{"de", "hotels.count", []interface{}{3}, "3 Hotels"},
+		{"de", "hotels.welcome", []interface{}{MessageArgs{"hotel": "Adlon"}}, `Willkommen im "Adlon"`},
+		// Merged with the goconfig and JSON files.
+		{"de", "greeting", nil, "Hallo"},
+		{"de-AT", "greeting", nil, "Servus"},
+		{"de-CH", "greeting", nil, "Grüezi"},
+	}
+	for _, test := range messageTests {
+		if message := Message(test.locale, test.key, test.args...); message != test.expected {
+			t.Errorf("%s %s: expected %q, got %q", test.locale, test.key, test.expected, message)
+		}
+	}
+
+	for _, key := range []string{"hotels.fuzzy", "hotels.untranslated", "save"} {
+		if _, found := findMessage("de", key, false); found {
+			t.Errorf("Expected no message %s", key)
+		}
+	}
+}
+
+func TestJsonMessages(t *testing.T) {
+	loadMessages(testCatalogsPath)
+
+	var messageTests = []struct {
+		key      string
+		count    int
+		expected string
+	}{
+		{"hotels.count", 1, "1 отель"},
+		{"hotels.count", 3, "3 отеля"},
+		{"hotels.count", 5, "5 отелей"},
+		{"hotels.count", 21, "21 отель"},
+		{"hotels.stars", 0, "5"},
+		{"greeting", 0, "Привет"},
+	}
+	for _, test := range messageTests {
+		if message := Message("ru", test.key, test.count); message != test.expected {
+			t.Errorf("%s %d: expected %q, got %q", test.key, test.count, test.expected, message)
+		}
+	}
+}
+
+func TestPoPluralForms(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "revel-messages")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.lv.po")
+	ioutil.WriteFile(path, []byte(`msgid ""
+msgstr ""
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n != 0 ? 1 : 2);\n"
+
+msgid "hotels.count"
+msgid_plural "hotels.count"
+msgstr[0] "{count} viesnīca"
+msgstr[1] "{count} viesnīcas"
+msgstr[2] "Nav viesnīcu"
+`), 0644)
+
+	_, messageConfig, err := ParseMessageFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for category, expected := range map[string]string{
+		"one":   "{count} viesnīca",
+		"other": "{count} viesnīcas",
+		"zero":  "Nav viesnīcu",
+	} {
+		if message, _ := messageConfig.String(config.DEFAULT_SECTION, "hotels.count."+category); message != expected {
+			t.Errorf("%s: expected %q, got %q", category, expected, message)
+		}
+	}
+}
+
+func TestPoPluralCategories(t *testing.T) {
+	var categoryTests = []struct {
+		nplurals, expr, language string
+		expected                 []string
+	}{
+		{"2", "(n != 1)", "de", []string{"one", "other"}},
+		{"1", "0", "ja", []string{"other"}},
+		{"3", "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)", "ru",
+			[]string{"one", "few", "many"}},
+		{"3", "n==0 ? 0 : n==1 ? 1 : 2", "lv", []string{"zero", "one", "other"}},
+	}
+	for _, test := range categoryTests {
+		categories, err := poPluralCategories(test.nplurals, test.expr, test.language)
+		if err != nil || !reflect.DeepEqual(categories, test.expected) {
+			t.Errorf("%s %s: expected %v, got %v (%v)", test.language, test.expr, test.expected, categories, err)
+		}
+	}
+
+	// Forms that do not match the rules of the language, and invalid ones.
+	for _, expr := range []string{"(n > 1)", "n % 3", "(n != 1", "n == one"} {
+		if categories, err := poPluralCategories("3", expr, "en"); err == nil {
+			t.Errorf("%s: expected an error, got %v", expr, categories)
+		}
+	}
+}
+
+func TestInvalidMessageFiles(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "revel-messages")
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"app.de.po":   "msgid \"greeting\"\nmsgstr Hallo\n",
+		"app.ru.json": `{"greeting": ["Привет"]}`,
+	} {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte(content), 0644)
+		if _, _, err := ParseMessageFile(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMessageLoaderRefresh(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "revel-messages")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.de.json")
+
+	ioutil.WriteFile(path, []byte(`{"greeting": "Hallo"}`), 0644)
+	loader := messageLoader{dir}
+	if err := loader.Refresh(); err != nil {
+		t.Fatalf("Failed to load the messages: %s", err.Description)
+	}
+	if message := Message("de", "greeting"); message != "Hallo" {
+		t.Errorf("Expected Hallo, got %s", message)
+	}
+
+	// A broken file keeps the previous messages.
+	ioutil.WriteFile(path, []byte(`{"greeting": `), 0644)
+	if err := loader.Refresh(); err == nil {
+		t.Errorf("Expected an error for the broken file")
+	}
+	if message := Message("de", "greeting"); message != "Hallo" {
+		t.Errorf("Expected the previous messages, got %s", message)
+	}
+
+	ioutil.WriteFile(path, []byte(`{"greeting": "Guten Tag"}`), 0644)
+	loader.Refresh()
+	if message := Message("de", "greeting"); message != "Guten Tag" {
+		t.Errorf("Expected Guten Tag after the change, got %s", message)
+	}
+}
+
+func TestEmbeddedMessageFiles(t *testing.T) {
+	RegisterEmbeddedFiles(1370000000, map[string]string{
+		"corp/sample/messages/app.de.po":   "msgid \"greeting\"\nmsgstr \"Hallo\"\n",
+		"corp/sample/messages/app.nl.json": `{"greeting": "Hallo!"}`,
+		"corp/sample/messages/app.en":      "greeting=Hello\n",
+	})
+	defer func() { embeddedFiles, embeddedDirs = nil, nil }()
+
+	loaded, err := readMessages(embeddedSourcePath + "/corp/sample/messages")
+	if err != nil {
+		t.Fatalf("Failed to read the embedded messages: %s", err)
+	}
+	for language, expected := range map[string]string{"de": "Hallo", "nl": "Hallo!", "en": "Hello"} {
+		if loaded[language] == nil {
+			t.Errorf("%s: expected the embedded messages to be loaded", language)
+			continue
+		}
+		if greeting, _ := loaded[language].String(config.DEFAULT_SECTION, "greeting"); greeting != expected {
+			t.Errorf("%s: expected %q, got %q", language, expected, greeting)
+		}
+	}
+}
@@ -1,26 +1,59 @@
 package controllers
 
 import (
-	"github.com/pyanfield/cron"
 	"github.com/pyanfield/revel"
 	"github.com/pyanfield/revel/modules/jobs/app/jobs"
 	"strings"
+	"time"
 )
 
 type Jobs struct {
 	*revel.Controller
 }
 
+// The state of a scheduled job, as shown on the status page.
+type jobStatus struct {
+	Name        string
+	Status      string
+	Prev, Next  time.Time // The last and next scheduled runs.
+	Successes   int
+	Failures    int
+	LastRun     *jobs.JobRun
+	LastFailure *jobs.JobRun
+	History     []jobs.JobRun // The last runs, the latest first.
+}
+
 func (c Jobs) Status() revel.Result {
 	if !strings.HasPrefix(c.Request.RemoteAddr, "127.0.0.1:") {
 		return c.Forbidden("%s is not local", c.Request.RemoteAddr)
 	}
-	entries := jobs.MainCron.Entries()
-	return c.Render(entries)
+	statuses := jobStatuses()
+	return c.Render(statuses)
+}
+
+// The data of the status page, for monitoring.
+func (c Jobs) StatusJson() revel.Result {
+	if !strings.HasPrefix(c.Request.RemoteAddr, "127.0.0.1:") {
+		return c.Forbidden("%s is not local", c.Request.RemoteAddr)
+	}
+	return c.RenderJson(jobStatuses())
 }
 
-func init() {
-	revel.TemplateFuncs["castjob"] = func(job cron.Job) *jobs.Job {
-		return job.(*jobs.Job)
+func jobStatuses() []jobStatus {
+	var statuses []jobStatus
+	for _, entry := range jobs.MainCron.Entries() {
+		job := entry.Job.(*jobs.Job)
+		statuses = append(statuses, jobStatus{
+			Name:        job.Name,
+			Status:      job.Status(),
+			Prev:        entry.Prev,
+			Next:        entry.Next,
+			Successes:   job.Successes(),
+			Failures:    job.Failures(),
+			LastRun:     job.LastRun(),
+			LastFailure: job.LastFailure(),
+			History:     job.History(),
+		})
 	}
+	return statuses
 }
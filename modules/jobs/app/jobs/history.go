@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"fmt"
+	"time"
+)
+
+// The number of runs kept in the history of each job, unless set by
+// jobs.history in app.conf.
+const DEFAULT_JOB_HISTORY_SIZE = 10
+
+var historySize = DEFAULT_JOB_HISTORY_SIZE
+
+// A run of a job.
+type JobRun struct {
+	Start    time.Time
+	Duration time.Duration
+	Error    string `json:",omitempty"` // Why the run failed, if it did.
+	Stack    string `json:",omitempty"` // Where the job panicked, if it did.
+	Stopped  bool   `json:",omitempty"` // If the run was cut short by the app stopping.
+}
+
+// The error of a job that panicked.
+type panicError struct {
+	value interface{}
+	stack string
+}
+
+func (e panicError) Error() string {
+	return fmt.Sprint(e.value)
+}
+
+// Add the run to the history of the job.  Runs cut short by the app stopping
+// are neither failures nor successes.
+func (j *Job) record(start time.Time, err error) {
+	run := JobRun{Start: start, Duration: time.Since(start)}
+	if err == errStopped {
+		run.Stopped = true
+	} else if err != nil {
+		run.Error = err.Error()
+		if panicErr, ok := err.(panicError); ok {
+			run.Stack = panicErr.stack
+		}
+	}
+
+	j.historyMu.Lock()
+	defer j.historyMu.Unlock()
+	switch {
+	case run.Stopped:
+	case err != nil:
+		j.failures++
+		j.lastFailure = &run
+	default:
+		j.successes++
+	}
+	if len(j.history) < historySize {
+		j.history = append(j.history, run)
+	} else if historySize > 0 {
+		j.history[j.historyNext] = run
+		j.historyNext = (j.historyNext + 1) % historySize
+	}
+}
+
+// Return the last runs of the job, the latest first.
+func (j *Job) History() []JobRun {
+	j.historyMu.Lock()
+	defer j.historyMu.Unlock()
+	history := make([]JobRun, 0, len(j.history))
+	for i := len(j.history) - 1; i >= 0; i-- {
+		history = append(history, j.history[(j.historyNext+i)%len(j.history)])
+	}
+	return history
+}
+
+// Return the last run of the job, or nil.
+func (j *Job) LastRun() *JobRun {
+	if history := j.History(); len(history) > 0 {
+		return &history[0]
+	}
+	return nil
+}
+
+// Return the last failed run of the job, or nil, even if it is no longer in
+// the history.
+func (j *Job) LastFailure() *JobRun {
+	j.historyMu.Lock()
+	defer j.historyMu.Unlock()
+	return j.lastFailure
+}
+
+// Return the number of successful runs.
+func (j *Job) Successes() int {
+	j.historyMu.Lock()
+	defer j.historyMu.Unlock()
+	return j.successes
+}
+
+// Return the number of failed runs: those that panicked, returned an error or
+// timed out.  (Each failed attempt of a retried job is counted)
+func (j *Job) Failures() int {
+	j.historyMu.Lock()
+	defer j.historyMu.Unlock()
+	return j.failures
+}
@@ -0,0 +1,83 @@
+package jobs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Record runs of the job, starting a second apart, failing those with odd
+// starts.
+func recordRuns(j *Job, starts ...int) {
+	for _, start := range starts {
+		var err error
+		if start%2 == 1 {
+			err = errors.New("failed")
+		}
+		j.record(time.Unix(int64(start), 0), err)
+	}
+}
+
+func historyStarts(j *Job) []int {
+	var starts []int
+	for _, run := range j.History() {
+		starts = append(starts, int(run.Start.Unix()))
+	}
+	return starts
+}
+
+func TestHistoryWrapsAround(t *testing.T) {
+	setupJobs(t, "")
+	historySize = 3
+	j := New(Func(func() {}))
+
+	for _, test := range []struct {
+		starts   []int
+		expected []int
+	}{
+		{[]int{1, 2}, []int{2, 1}},
+		{[]int{3}, []int{3, 2, 1}},
+		{[]int{4}, []int{4, 3, 2}},
+		{[]int{5, 6, 7, 8}, []int{8, 7, 6}},
+	} {
+		recordRuns(j, test.starts...)
+		if actual := historyStarts(j); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("After %v: expected the history %v, got %v", test.starts, test.expected, actual)
+		}
+	}
+	if j.Successes() != 4 || j.Failures() != 4 || j.LastFailure().Start.Unix() != 7 || j.LastRun().Start.Unix() != 8 {
+		t.Errorf("Unexpected counts: %d successes, %d failures, last failure %v, last run %v",
+			j.Successes(), j.Failures(), j.LastFailure(), j.LastRun())
+	}
+}
+
+func TestNoHistory(t *testing.T) {
+	setupJobs(t, "")
+	historySize = 0
+	j := New(Func(func() {}))
+
+	recordRuns(j, 1, 2, 3)
+	if history := j.History(); len(history) != 0 || j.LastRun() != nil {
+		t.Errorf("Expected no history, got %v", history)
+	}
+	if j.Successes() != 1 || j.Failures() != 2 || j.LastFailure().Start.Unix() != 3 {
+		t.Errorf("Expected the runs to be counted without a history, got %d successes and %d failures",
+			j.Successes(), j.Failures())
+	}
+}
+
+func TestStoppedRunIsNotAFailure(t *testing.T) {
+	setupJobs(t, "")
+	j := New(Func(func() {}))
+
+	recordRuns(j, 1)
+	j.record(time.Unix(2, 0), errStopped)
+	if j.Failures() != 1 || j.Successes() != 0 || j.LastFailure().Start.Unix() != 1 {
+		t.Errorf("Expected only the first run to fail: %d failures, %d successes, last failure %v",
+			j.Failures(), j.Successes(), j.LastFailure())
+	}
+	if run := j.LastRun(); run.Start.Unix() != 2 || !run.Stopped || run.Error != "" {
+		t.Errorf("Expected the last run to be stopped, got %+v", run)
+	}
+}
@@ -1,12 +1,16 @@
 package jobs
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/pyanfield/cron"
 	"github.com/pyanfield/revel"
 	"reflect"
 	"runtime/debug"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type Job struct {
@@ -14,53 +18,229 @@ type Job struct {
 	inner   cron.Job
 	status  uint32
 	running sync.Mutex
+
+	timeout time.Duration // The timeout of each run, or -1 for the default one.
+	retry   RetryPolicy   // How the job is retried when it fails.
+
+	abandonedMu  sync.Mutex
+	abandonedRun chan struct{} // Closed when the last abandoned run returns.
+
+	historyMu   sync.Mutex
+	history     []JobRun // The last runs, in a ring buffer of jobs.history runs.
+	historyNext int      // The index of the next run in history, once it is full.
+	successes   int
+	failures    int
+	lastFailure *JobRun
 }
 
 const UNNAMED = "(unnamed)"
 
 func New(job cron.Job) *Job {
-	name := reflect.TypeOf(job).Name()
-	if name == "Func" {
-		name = UNNAMED
+	j := &Job{timeout: -1}
+	for j.inner == nil {
+		switch wrapper := job.(type) {
+		case timeoutJob:
+			job, j.timeout = wrapper.Job, wrapper.timeout
+		case retryJob:
+			job, j.retry = wrapper.Job, wrapper.policy
+		default:
+			j.inner = job
+		}
 	}
-	return &Job{
-		Name:  name,
-		inner: job,
+
+	j.Name = reflect.TypeOf(job).Name()
+	switch j.Name {
+	case "Func", "ContextFunc", "ErrorFunc":
+		j.Name = UNNAMED
 	}
+	return j
 }
 
+// The status of a job.
+const (
+	jobIdle = iota
+	jobRunning
+	jobAbandoned // A run timed out, but has not returned yet.
+)
+
 func (j *Job) Status() string {
-	if atomic.LoadUint32(&j.status) > 0 {
+	switch atomic.LoadUint32(&j.status) {
+	case jobRunning:
 		return "RUNNING"
+	case jobAbandoned:
+		return "ABANDONED"
 	}
 	return "IDLE"
 }
 
+// Return the timeout of each run, or 0 for none.
+func (j *Job) Timeout() time.Duration {
+	if j.timeout < 0 {
+		return defaultTimeout
+	}
+	return j.timeout
+}
+
 func (j *Job) Run() {
-	// If the job panics, just print a stack trace.
-	// Don't let the whole process die.
-	defer func() {
-		if err := recover(); err != nil {
-			if revelError := revel.NewErrorFromPanic(err); revelError != nil {
-				revel.ERROR.Print(err, "\n", revelError.Stack)
-			} else {
-				revel.ERROR.Print(err, "\n", string(debug.Stack()))
-			}
+	j.attempt(1, nil)
+}
+
+// Run the job, retrying it as its retry policy allows if it fails.  A retry
+// is given the failure of the previous attempt.
+func (j *Job) attempt(n int, previous *Failure) {
+	switch err := j.runOnce(); {
+	case err == errStopped && previous != nil:
+		dropRetry(*previous)
+	case err != nil && err != errStopped:
+		if failure, delay, retry := j.failed(n, err); retry {
+			j.scheduleRetry(failure, delay)
 		}
-	}()
+	}
+}
 
-	if !selfConcurrent {
-		j.running.Lock()
-		defer j.running.Unlock()
+// Record the failed attempt and tell the failure hooks.  Returns the delay
+// before the next attempt, if the retry policy allows one.
+func (j *Job) failed(n int, err error) (failure Failure, delay time.Duration, retry bool) {
+	failure = Failure{Job: j, Attempt: n, Err: err, GaveUp: n >= j.retry.MaxAttempts}
+	revel.ERROR.Printf("Job %s failed (attempt %d): %s", j.Name, n, err)
+	notifyFailure(failure)
+	if failure.GaveUp {
+		return failure, 0, false
+	}
+
+	delay = j.retry.delay(n)
+	revel.INFO.Printf("Retrying job %s in %s", j.Name, delay)
+	return failure, delay, true
+}
+
+// Retry the job after the delay, once its abandoned run, if any, has returned.
+func (j *Job) scheduleRetry(failure Failure, delay time.Duration) {
+	retry := &pendingRetry{failure: failure}
+	retriesMu.Lock()
+	defer retriesMu.Unlock()
+	retry.timer = time.AfterFunc(delay, func() {
+		if takeRetry(retry) {
+			j.waitForAbandonedRun()
+			j.attempt(failure.Attempt+1, &failure)
+		}
+	})
+	pendingRetries[retry] = true
+}
+
+// Wait until the last abandoned run of the job, if any, has returned.
+func (j *Job) waitForAbandonedRun() {
+	j.abandonedMu.Lock()
+	abandonedRun := j.abandonedRun
+	j.abandonedMu.Unlock()
+	if abandonedRun != nil {
+		<-abandonedRun
+	}
+}
+
+// Returned by runOnce for jobs not run, or cut short, because the app is stopping.
+var errStopped = errors.New("the app is stopping")
+
+// Run the job once, returning why it failed, if it did.
+func (j *Job) runOnce() (err error) {
+	if stopped() {
+		revel.WARN.Printf("Not running job %s: the app is stopping", j.Name)
+		return errStopped
 	}
 
+	// The lock and the permit are held until the run returns, even if it
+	// is abandoned, so that abandoned runs still count against the limits.
+	locked := !selfConcurrent
+	if locked {
+		j.running.Lock()
+	}
 	if workPermits != nil {
 		workPermits <- struct{}{}
-		defer func() { <-workPermits }()
 	}
+	permits := workPermits
+	release := func() {
+		if permits != nil {
+			<-permits
+		}
+		if locked {
+			j.running.Unlock()
+		}
+		atomic.StoreUint32(&j.status, jobIdle)
+	}
+	atomic.StoreUint32(&j.status, jobRunning)
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if timeout := j.Timeout(); timeout > 0 {
+		ctx, cancel = context.WithTimeout(stopContext, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(stopContext)
+	}
+	defer cancel()
 
-	atomic.StoreUint32(&j.status, 1)
-	defer atomic.StoreUint32(&j.status, 0)
+	start := time.Now()
+	defer func() { j.record(start, err) }()
 
-	j.inner.Run()
+	var runErr error
+	done := make(chan struct{})
+	activeRuns.Add(1)
+	go func() {
+		defer activeRuns.Done()
+		defer close(done)
+		runErr = j.run(ctx)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if ctx.Err() == context.Canceled {
+			// The app is stopping: it waits for the run, for the grace period.
+			<-done
+			break
+		}
+		// Abandon the run, which keeps the lock and permit until it returns.
+		atomic.StoreUint32(&j.status, jobAbandoned)
+		j.abandonedMu.Lock()
+		j.abandonedRun = done
+		j.abandonedMu.Unlock()
+		go func() {
+			<-done
+			revel.WARN.Printf("Abandoned run of job %s returned after %s", j.Name, time.Since(start))
+			release()
+		}()
+		return fmt.Errorf("timed out after %s", j.Timeout())
+	}
+
+	release()
+	if ctx.Err() == context.Canceled {
+		// (The run was cut short, whatever it returned)
+		return errStopped
+	}
+	return runErr
+}
+
+func (j *Job) run(ctx context.Context) (err error) {
+	// If the job panics, just print a stack trace.
+	// Don't let the whole process die.
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			stack := string(debug.Stack())
+			if revelError := revel.NewErrorFromPanic(recovered); revelError != nil {
+				stack = revelError.Stack
+			}
+			revel.ERROR.Print(recovered, "\n", stack)
+			err = panicError{recovered, stack}
+		}
+	}()
+
+	switch inner := j.inner.(type) {
+	case ErrorJob:
+		return inner.RunError(ctx)
+	case ContextJob:
+		inner.RunContext(ctx)
+	default:
+		inner.Run()
+	}
+	return nil
 }
@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"context"
+	"github.com/pyanfield/cron"
+	"github.com/pyanfield/revel"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Reset the state of the jobs, with the given app.conf.
+func setupJobs(t *testing.T, conf string) {
+	dir, err := ioutil.TempDir("", "revel-jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "app.conf"), []byte(conf), 0644)
+	revel.ConfPaths = []string{dir}
+	if revel.Config, err = revel.LoadConfig("app.conf"); err != nil {
+		t.Fatal(err)
+	}
+
+	stopContext, stopJobs = context.WithCancel(context.Background())
+	workPermits, selfConcurrent, defaultTimeout = nil, false, 0
+	historySize = DEFAULT_JOB_HISTORY_SIZE
+	failureHooks, giveUpHooks = nil, nil
+	pendingRetries = map[*pendingRetry]bool{}
+	queueTypes, queueTypeNames, QueueStore = map[string]*queueType{}, map[reflect.Type]string{}, nil
+	dueJobs = nil
+	MainCron = cron.New()
+}
+
+// A job that counts its runs, and blocks until it is released, ignoring its
+// context.
+type blockingJob struct {
+	runs    *int32
+	release chan struct{}
+}
+
+func newBlockingJob() blockingJob {
+	return blockingJob{new(int32), make(chan struct{})}
+}
+
+func (j blockingJob) Run() {
+	atomic.AddInt32(j.runs, 1)
+	<-j.release
+}
+
+func (j blockingJob) Runs() int32 {
+	return atomic.LoadInt32(j.runs)
+}
+
+// Run the job in the background, returning a channel that receives its error.
+func runInBackground(j *Job) chan error {
+	result := make(chan error, 1)
+	go func() { result <- j.runOnce() }()
+	return result
+}
+
+func TestAbandonedRunKeepsTheLock(t *testing.T) {
+	setupJobs(t, "")
+	inner := newBlockingJob()
+	j := New(WithTimeout(20*time.Millisecond, inner))
+
+	if err := j.runOnce(); err == nil {
+		t.Fatal("Expected the run to time out")
+	}
+	if j.Status() != "ABANDONED" {
+		t.Errorf("Expected status ABANDONED, got %s", j.Status())
+	}
+
+	// The next run waits for the abandoned one.
+	result := runInBackground(j)
+	time.Sleep(30 * time.Millisecond)
+	if inner.Runs() != 1 {
+		t.Errorf("Expected the next run to wait for the abandoned one")
+	}
+	close(inner.release)
+	if err := <-result; err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if inner.Runs() != 2 || j.Status() != "IDLE" {
+		t.Errorf("Expected 2 runs and status IDLE, got %d and %s", inner.Runs(), j.Status())
+	}
+}
+
+func TestAbandonedRunKeepsItsPermit(t *testing.T) {
+	setupJobs(t, "")
+	workPermits, selfConcurrent = make(chan struct{}, 1), true
+	abandoned, next := newBlockingJob(), newBlockingJob()
+	close(next.release)
+
+	if err := New(WithTimeout(20*time.Millisecond, abandoned)).runOnce(); err == nil {
+		t.Fatal("Expected the run to time out")
+	}
+	result := runInBackground(New(next))
+	time.Sleep(30 * time.Millisecond)
+	if next.Runs() != 0 {
+		t.Errorf("Expected the next job to wait for the permit of the abandoned one")
+	}
+	close(abandoned.release)
+	if err := <-result; err != nil || next.Runs() != 1 {
+		t.Errorf("Expected the next job to run, got %d runs (%v)", next.Runs(), err)
+	}
+}
+
+func TestRunIsCanceledOnStop(t *testing.T) {
+	setupJobs(t, "")
+	canceled := make(chan struct{})
+	j := New(ContextFunc(func(ctx context.Context) {
+		<-ctx.Done()
+		close(canceled)
+	}))
+
+	result := runInBackground(j)
+	time.Sleep(10 * time.Millisecond)
+	stopJobs()
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the context of the run to be canceled")
+	}
+	if err := <-result; err != errStopped {
+		t.Errorf("Expected the canceled run to return errStopped, got %v", err)
+	}
+
+	if err := j.runOnce(); err != errStopped {
+		t.Errorf("Expected no more runs once stopped, got %v", err)
+	}
+}
+
+func TestStopWaitsForTheGracePeriod(t *testing.T) {
+	setupJobs(t, "")
+	inner := newBlockingJob()
+	result := runInBackground(New(inner))
+	time.Sleep(10 * time.Millisecond)
+
+	start := time.Now()
+	stopJobs()
+	done := waitForRuns(50 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected to wait for the grace period, waited %s", elapsed)
+	}
+	close(inner.release)
+	<-result
+	<-done
+}
+
+func TestStopReturnsWhenTheRunsReturn(t *testing.T) {
+	setupJobs(t, "jobs.grace = 10s\n")
+	MainCron.Start()
+	result := runInBackground(New(ContextFunc(func(ctx context.Context) { <-ctx.Done() })))
+	time.Sleep(10 * time.Millisecond)
+
+	start := time.Now()
+	JobsPlugin{}.OnAppStop()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected to stop once the run returned, waited %s", elapsed)
+	}
+	<-result
+}
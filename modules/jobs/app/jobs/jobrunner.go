@@ -7,10 +7,21 @@
 // 3. (Optional) Protection against multiple instances of a single job running
 //    concurrently.  If one execution runs into the next, the next will be queued.
 // 4. Cron expressions may be defined in app.conf and are reusable across jobs.
-// 5. Job status reporting.
+// 5. Job status reporting, with the history of each scheduled job, at /@jobs
+//    (and as JSON at /@jobs.json).  Ad-hoc jobs (Now, In) are not shown.
+// 6. (Optional) Retries of failed jobs, with exponential backoff, and hooks to
+//    tell of failures.
+// 7. (Optional) Timeouts (jobs.timeout), after which a job run is abandoned and
+//    recorded as a failure.  (It keeps its place in the pool, and the job is not
+//    run again, until it returns)  And a grace period (jobs.grace) for running
+//    jobs when the app stops.  (With app.handle_signals, or revel.StopApp)  Runs
+//    cut short by the app stopping are not recorded as failures.
+// 8. (Optional) A queue of jobs that are saved until they are done, so that they
+//    survive restarts.
 package jobs
 
 import (
+	"context"
 	"github.com/pyanfield/cron"
 	"github.com/pyanfield/revel"
 	"strings"
@@ -26,6 +37,69 @@ type Func func()
 
 func (r Func) Run() { r() }
 
+// A job that is given a context, which is canceled when the run times out or
+// the app stops.  Long running jobs should check ctx.Done() and return early.
+// (It is still scheduled as a cron.Job, whose Run is not called)
+type ContextJob interface {
+	cron.Job
+	RunContext(ctx context.Context)
+}
+
+// Callers can use jobs.ContextFunc to wrap a raw func that takes a context.
+//
+// For example:
+//    jobs.Every(time.Hour, jobs.ContextFunc(func(ctx context.Context) {
+//        for _, report := range reports {
+//            select {
+//            case <-ctx.Done():
+//                return
+//            default:
+//                report.Send()
+//            }
+//        }
+//    }))
+type ContextFunc func(ctx context.Context)
+
+func (r ContextFunc) Run()                           { r(context.Background()) }
+func (r ContextFunc) RunContext(ctx context.Context) { r(ctx) }
+
+// A job that fails by returning an error, rather than by panicking.  It is
+// given a context, like a ContextJob.
+type ErrorJob interface {
+	cron.Job
+	RunError(ctx context.Context) error
+}
+
+// Callers can use jobs.ErrorFunc to wrap a raw func that may fail.
+//
+// For example:
+//    jobs.Now(jobs.WithRetry(jobs.DefaultRetryPolicy, jobs.ErrorFunc(func(ctx context.Context) error {
+//        return mailer.Send(confirmation)
+//    })))
+type ErrorFunc func(ctx context.Context) error
+
+func (r ErrorFunc) Run() {
+	if err := r(context.Background()); err != nil {
+		panic(err)
+	}
+}
+func (r ErrorFunc) RunError(ctx context.Context) error { return r(ctx) }
+
+// A job with its own timeout.
+type timeoutJob struct {
+	cron.Job
+	timeout time.Duration
+}
+
+// Give the job its own timeout, instead of the default one (jobs.timeout).
+// A timeout of 0 means none.
+//
+// For example:
+//    jobs.Schedule("cron.hourly", jobs.WithTimeout(5*time.Minute, ReportJob{}))
+func WithTimeout(timeout time.Duration, job cron.Job) cron.Job {
+	return timeoutJob{job, timeout}
+}
+
 func Schedule(spec string, job cron.Job) {
 	// Look to see if given spec is a key from the Config.
 	if strings.HasPrefix(spec, "cron.") {
@@ -45,12 +119,14 @@ func Every(duration time.Duration, job cron.Job) {
 	MainCron.Schedule(cron.Every(duration), New(job))
 }
 
-// Run the given job right now.
+// Run the given job right now.  (It has no history: only scheduled jobs are
+// shown at /@jobs)
 func Now(job cron.Job) {
 	go New(job).Run()
 }
 
-// Run the given job once, after the given delay.
+// Run the given job once, after the given delay.  (It has no history: only
+// scheduled jobs are shown at /@jobs)
 func In(duration time.Duration, job cron.Job) {
 	go func() {
 		time.Sleep(duration)
@@ -1,12 +1,18 @@
 package jobs
 
 import (
+	"context"
 	"fmt"
 	"github.com/pyanfield/cron"
 	"github.com/pyanfield/revel"
+	"sync"
+	"time"
 )
 
-const DEFAULT_JOB_POOL_SIZE = 10
+const (
+	DEFAULT_JOB_POOL_SIZE    = 10
+	DEFAULT_JOB_GRACE_PERIOD = 10 * time.Second
+)
 
 var (
 	// Singleton instance of the underlying job scheduler.
@@ -17,26 +23,94 @@ var (
 
 	// Is a single job allowed to run concurrently with itself?
 	selfConcurrent bool
+
+	// The timeout of job runs, unless the job has its own.  (0 for none)
+	defaultTimeout time.Duration
+
+	// Canceled when the app stops, and with it the contexts of job runs.
+	stopContext context.Context
+	stopJobs    context.CancelFunc
+
+	// The job runs in progress, including those abandoned after timing out.
+	activeRuns sync.WaitGroup
 )
 
+// Return true if the app is stopping, so that no more jobs may run.
+func stopped() bool {
+	return stopContext.Err() != nil
+}
+
+// Read a duration from app.conf, e.g. jobs.timeout=5m
+func configDuration(key string, defaultValue time.Duration) time.Duration {
+	value := revel.Config.StringDefault(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		panic("Invalid duration for " + key + ": " + value)
+	}
+	return duration
+}
+
 type JobsPlugin struct {
 	revel.EmptyPlugin
 }
 
 func (p JobsPlugin) OnAppStart() {
-	if size := revel.Config.IntDefault("jobs.pool", DEFAULT_JOB_POOL_SIZE); size > 0 {
+	size := revel.Config.IntDefault("jobs.pool", DEFAULT_JOB_POOL_SIZE)
+	if size > 0 {
 		workPermits = make(chan struct{}, size)
+	} else {
+		size = DEFAULT_JOB_POOL_SIZE
 	}
 	selfConcurrent = revel.Config.BoolDefault("jobs.selfconcurrent", false)
+	defaultTimeout = configDuration("jobs.timeout", 0)
+	historySize = revel.Config.IntDefault("jobs.history", DEFAULT_JOB_HISTORY_SIZE)
 	MainCron.Start()
+
+	// Start the queue, if the app has types of queued jobs.
+	if len(queueTypes) > 0 {
+		if QueueStore == nil {
+			store, err := configQueueStore()
+			if err != nil {
+				revel.ERROR.Fatalln("Failed to open the job queue:", err)
+			}
+			QueueStore = store
+		}
+		startQueue(size)
+	}
 }
 
+// Stop scheduling jobs, give up the retries that are waiting, cancel the
+// running jobs, and wait for them to return for the grace period (jobs.grace),
+// after which they are abandoned.
 func (p JobsPlugin) OnAppStop() {
 	MainCron.Stop()
+	stopJobs()
+	dropRetries()
+	waitForRuns(configDuration("jobs.grace", DEFAULT_JOB_GRACE_PERIOD))
+}
+
+// Wait for the runs in progress to return, for at most the grace period.
+// Returns a channel that is closed once they have.
+func waitForRuns(grace time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		activeRuns.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(grace):
+		revel.WARN.Printf("Abandoning the running jobs after %s", grace)
+	}
+	return done
 }
 
 func (t JobsPlugin) OnRoutesLoaded(router *revel.Router) {
 	router.Routes = append([]*revel.Route{
+		revel.NewRoute("GET", "/@jobs.json", "Jobs.StatusJson", ""),
 		revel.NewRoute("GET", "/@jobs", "Jobs.Status", ""),
 	}, router.Routes...)
 	fmt.Println("Go to /@jobs to see job status.")
@@ -44,5 +118,6 @@ func (t JobsPlugin) OnRoutesLoaded(router *revel.Router) {
 
 func init() {
 	MainCron = cron.New()
+	stopContext, stopJobs = context.WithCancel(context.Background())
 	revel.RegisterPlugin(JobsPlugin{})
 }
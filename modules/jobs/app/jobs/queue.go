@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pyanfield/cron"
+	"github.com/pyanfield/revel"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// Jobs given to Now and In are lost when the app stops.  Jobs added to the
+// queue are saved in the queue store (see QueueStore), and run by the queue
+// workers, even after a restart.  Jobs that were running when the app stopped
+// are run again, so they should be safe to run more than once.
+//
+// The types of queued jobs are registered by name at startup, e.g. in an init
+// func, with their retry policy and timeout, if any.  Their fields are saved
+// as JSON, so they must be exported.
+//
+//   type SendReminder struct {
+//       BookingId int
+//   }
+//
+//   func (j SendReminder) Run() { ... }
+//
+//   func init() {
+//       jobs.Register("reminder", jobs.WithRetry(jobs.DefaultRetryPolicy, SendReminder{}))
+//   }
+//
+//   jobs.EnqueueIn(2*time.Hour, SendReminder{booking.Id})
+
+// A job saved in the queue store.
+type QueuedJob struct {
+	Id      string
+	Type    string          // The registered name of the job type.
+	Payload json.RawMessage // The job, as JSON.
+	RunAt   time.Time       // When the job is due.
+	Attempt int             // The number of attempts so far.
+}
+
+// A registered type of queued jobs.
+type queueType struct {
+	name    string
+	typ     reflect.Type
+	timeout time.Duration
+	retry   RetryPolicy
+}
+
+var (
+	queueTypes     = map[string]*queueType{}
+	queueTypeNames = map[reflect.Type]string{}
+
+	// Receives the queued jobs that are due, for the workers.
+	dueJobs chan *QueuedJob
+
+	// The last id given to a queued job, in this process.
+	lastQueuedJobId uint64
+)
+
+// Register a type of queued jobs, by name.  The job may be wrapped by
+// WithRetry or WithTimeout, whose options apply to all jobs of the type.
+func Register(name string, job cron.Job) {
+	j := New(job)
+	typ := reflect.TypeOf(j.inner)
+	if _, found := queueTypes[name]; found {
+		panic("Queued job type already registered: " + name)
+	}
+	queueTypes[name] = &queueType{name, typ, j.timeout, j.retry}
+	queueTypeNames[typ] = name
+}
+
+// Add the job to the queue, to run as soon as a worker is free.
+func Enqueue(job cron.Job) error {
+	return EnqueueIn(0, job)
+}
+
+// Add the job to the queue, to run after the given delay.  The queue starts
+// with the app, so jobs may not be added before, e.g. in init funcs.
+func EnqueueIn(duration time.Duration, job cron.Job) error {
+	if dueJobs == nil {
+		return fmt.Errorf("jobs: the queue is not started")
+	}
+	name, found := queueTypeNames[reflect.TypeOf(job)]
+	if !found {
+		return fmt.Errorf("jobs: job type %s is not registered", reflect.TypeOf(job))
+	}
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	queuedJob := &QueuedJob{
+		Id:      newQueuedJobId(),
+		Type:    name,
+		Payload: payload,
+		RunAt:   time.Now().Add(duration),
+	}
+	if err = QueueStore.Add(queuedJob); err != nil {
+		return err
+	}
+	scheduleQueuedJob(queuedJob)
+	return nil
+}
+
+// Return a new id, unique across restarts, e.g. 1381337733000000000-1
+func newQueuedJobId() string {
+	return fmt.Sprintf("%d-%d", time.Now().UnixNano(), atomic.AddUint64(&lastQueuedJobId, 1))
+}
+
+// Give the job to the workers when it is due.
+func scheduleQueuedJob(queuedJob *QueuedJob) {
+	time.AfterFunc(queuedJob.RunAt.Sub(time.Now()), func() {
+		select {
+		case dueJobs <- queuedJob:
+		case <-stopContext.Done():
+		}
+	})
+}
+
+// Start the workers, and schedule the jobs left in the store.
+func startQueue(workers int) {
+	dueJobs = make(chan *QueuedJob)
+	for i := 0; i < workers; i++ {
+		go queueWorker(dueJobs, stopContext.Done())
+	}
+
+	pending, err := QueueStore.Pending()
+	if err != nil {
+		revel.ERROR.Println("Failed to read the queued jobs:", err)
+		return
+	}
+	if len(pending) > 0 {
+		revel.INFO.Printf("Resuming %d queued jobs", len(pending))
+	}
+	for _, queuedJob := range pending {
+		scheduleQueuedJob(queuedJob)
+	}
+}
+
+// Run the jobs that are due, until the app stops.
+func queueWorker(due <-chan *QueuedJob, stop <-chan struct{}) {
+	for {
+		select {
+		case queuedJob := <-due:
+			runQueuedJob(queuedJob)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Run the queued job, and remove it from the store when it is done, or save
+// when to retry it.
+func runQueuedJob(queuedJob *QueuedJob) {
+	qt, found := queueTypes[queuedJob.Type]
+	if !found {
+		// (Keep it, for a version of the app that has the type)
+		revel.ERROR.Printf("Queued job %s has the unregistered type %s", queuedJob.Id, queuedJob.Type)
+		return
+	}
+	job, err := qt.decode(queuedJob.Payload)
+	if err != nil {
+		revel.ERROR.Printf("Removing queued job %s, which can not be read: %s", queuedJob.Id, err)
+		removeQueuedJob(queuedJob)
+		return
+	}
+
+	// Count the attempt before running it, in case it takes down the process.
+	queuedJob.Attempt++
+	if err = QueueStore.Update(queuedJob); err != nil {
+		revel.ERROR.Printf("Failed to update queued job %s: %s", queuedJob.Id, err)
+	}
+
+	j := New(job)
+	j.Name, j.timeout, j.retry = qt.name, qt.timeout, qt.retry
+	switch err = j.runOnce(); err {
+	case nil:
+		removeQueuedJob(queuedJob)
+	case errStopped:
+		// (It is run after the restart)
+	default:
+		_, delay, retry := j.failed(queuedJob.Attempt, err)
+		if !retry {
+			removeQueuedJob(queuedJob)
+			return
+		}
+		queuedJob.RunAt = time.Now().Add(delay)
+		if err = QueueStore.Update(queuedJob); err != nil {
+			revel.ERROR.Printf("Failed to update queued job %s: %s", queuedJob.Id, err)
+		}
+		scheduleQueuedJob(queuedJob)
+	}
+}
+
+func removeQueuedJob(queuedJob *QueuedJob) {
+	if err := QueueStore.Remove(queuedJob); err != nil {
+		revel.ERROR.Printf("Failed to remove queued job %s: %s", queuedJob.Id, err)
+	}
+}
+
+// Return the job of the registered type, read from the payload.
+func (qt *queueType) decode(payload json.RawMessage) (cron.Job, error) {
+	if qt.typ.Kind() == reflect.Ptr {
+		job := reflect.New(qt.typ.Elem())
+		err := json.Unmarshal(payload, job.Interface())
+		return job.Interface().(cron.Job), err
+	}
+	job := reflect.New(qt.typ)
+	err := json.Unmarshal(payload, job.Interface())
+	return job.Elem().Interface().(cron.Job), err
+}
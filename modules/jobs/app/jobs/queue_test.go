@@ -0,0 +1,222 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A store of the queued jobs in memory.
+type memStore struct {
+	sync.Mutex
+	jobs    map[string]QueuedJob
+	updates int
+}
+
+func newMemStore() *memStore {
+	return &memStore{jobs: map[string]QueuedJob{}}
+}
+
+func (s *memStore) Add(job *QueuedJob) error {
+	s.Lock()
+	defer s.Unlock()
+	s.jobs[job.Id] = *job
+	return nil
+}
+
+func (s *memStore) Update(job *QueuedJob) error {
+	s.Lock()
+	defer s.Unlock()
+	s.jobs[job.Id] = *job
+	s.updates++
+	return nil
+}
+
+func (s *memStore) Remove(job *QueuedJob) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.jobs, job.Id)
+	return nil
+}
+
+func (s *memStore) Pending() ([]*QueuedJob, error) {
+	s.Lock()
+	defer s.Unlock()
+	var jobs []*QueuedJob
+	for _, job := range s.jobs {
+		job := job
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+func (s *memStore) job(id string) (QueuedJob, bool) {
+	s.Lock()
+	defer s.Unlock()
+	job, found := s.jobs[id]
+	return job, found
+}
+
+// A queued job that sends its name to greeted, and fails if it is told to.
+type greetJob struct {
+	Name string
+	Fail bool
+}
+
+var greeted = make(chan string, 10)
+
+func (j greetJob) Run() {}
+
+func (j greetJob) RunError(ctx context.Context) error {
+	greeted <- j.Name
+	if j.Fail {
+		return errors.New("failed to greet " + j.Name)
+	}
+	return nil
+}
+
+// A queued job that waits for the app to stop.
+type waitJob struct{}
+
+var waitStarted = make(chan struct{}, 1)
+
+func (j waitJob) Run() {}
+
+func (j waitJob) RunContext(ctx context.Context) {
+	waitStarted <- struct{}{}
+	<-ctx.Done()
+}
+
+// Register the job types of the tests, and return a queued job in the store.
+func setupQueue(t *testing.T, job greetJob) (*memStore, *QueuedJob) {
+	setupJobs(t, "")
+	Register("greet", WithRetry(RetryPolicy{MaxAttempts: 2, Backoff: time.Hour}, greetJob{}))
+	Register("greetPtr", &greetJob{})
+	Register("wait", waitJob{})
+	store := newMemStore()
+	QueueStore = store
+
+	payload, _ := json.Marshal(job)
+	queuedJob := &QueuedJob{Id: newQueuedJobId(), Type: "greet", Payload: payload, RunAt: time.Now()}
+	store.Add(queuedJob)
+	return store, queuedJob
+}
+
+func TestQueueTypeDecode(t *testing.T) {
+	setupQueue(t, greetJob{})
+	payload := json.RawMessage(`{"Name": "Ana"}`)
+
+	job, err := queueTypes["greet"].decode(payload)
+	if j, ok := job.(greetJob); err != nil || !ok || j.Name != "Ana" {
+		t.Errorf("Expected greetJob{Ana}, got %#v (%v)", job, err)
+	}
+	job, err = queueTypes["greetPtr"].decode(payload)
+	if j, ok := job.(*greetJob); err != nil || !ok || j.Name != "Ana" {
+		t.Errorf("Expected &greetJob{Ana}, got %#v (%v)", job, err)
+	}
+	if _, err = queueTypes["greet"].decode(json.RawMessage(`{"Name": 1}`)); err == nil {
+		t.Error("Expected an error for a payload of the wrong type")
+	}
+}
+
+func TestRunQueuedJobRemovesItWhenDone(t *testing.T) {
+	store, queuedJob := setupQueue(t, greetJob{Name: "Ana"})
+	runQueuedJob(queuedJob)
+	if name := <-greeted; name != "Ana" {
+		t.Errorf("Expected to greet Ana, greeted %s", name)
+	}
+	if _, found := store.job(queuedJob.Id); found {
+		t.Error("Expected the job to be removed once done")
+	}
+	if store.updates != 1 {
+		t.Errorf("Expected the attempt to be saved before the run, got %d updates", store.updates)
+	}
+}
+
+func TestRunQueuedJobRetriesIt(t *testing.T) {
+	store, queuedJob := setupQueue(t, greetJob{Name: "Ana", Fail: true})
+	defer stopJobs()
+
+	runQueuedJob(queuedJob)
+	<-greeted
+	saved, found := store.job(queuedJob.Id)
+	if !found || saved.Attempt != 1 || saved.RunAt.Before(time.Now().Add(30*time.Minute)) {
+		t.Errorf("Expected the job to be kept for a retry, got %+v (found: %v)", saved, found)
+	}
+
+	// The second attempt is the last.
+	runQueuedJob(queuedJob)
+	<-greeted
+	if _, found := store.job(queuedJob.Id); found {
+		t.Error("Expected the job to be removed once given up")
+	}
+}
+
+func TestRunQueuedJobKeepsItWhenStopped(t *testing.T) {
+	store, queuedJob := setupQueue(t, greetJob{})
+	queuedJob.Type = "wait"
+	done := make(chan struct{})
+	go func() {
+		runQueuedJob(queuedJob)
+		close(done)
+	}()
+
+	<-waitStarted
+	stopJobs()
+	<-done
+	if saved, found := store.job(queuedJob.Id); !found || saved.Attempt != 1 {
+		t.Errorf("Expected the canceled job to be kept for the restart, got %+v (found: %v)", saved, found)
+	}
+}
+
+func TestRunQueuedJobOfUnknownType(t *testing.T) {
+	store, queuedJob := setupQueue(t, greetJob{})
+	queuedJob.Type = "unknown"
+	runQueuedJob(queuedJob)
+	if _, found := store.job(queuedJob.Id); !found {
+		t.Error("Expected the job of an unregistered type to be kept")
+	}
+
+	queuedJob.Type, queuedJob.Payload = "greet", json.RawMessage(`{"Name": 1}`)
+	runQueuedJob(queuedJob)
+	if _, found := store.job(queuedJob.Id); found {
+		t.Error("Expected the job that can not be read to be removed")
+	}
+}
+
+func TestEnqueueOnceTheQueueIsStarted(t *testing.T) {
+	store, queuedJob := setupQueue(t, greetJob{})
+	store.Remove(queuedJob)
+	defer stopJobs()
+
+	if err := Enqueue(greetJob{Name: "Ana"}); err == nil {
+		t.Error("Expected an error for a job enqueued before the queue is started")
+	}
+	if pending, _ := store.Pending(); len(pending) != 0 {
+		t.Errorf("Expected the job not to be saved, got %v", pending)
+	}
+
+	startQueue(1)
+	if err := Enqueue(greetJob{Name: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case name := <-greeted:
+		if name != "Bob" {
+			t.Errorf("Expected to greet Bob, greeted %s", name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the enqueued job to run")
+	}
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		if pending, _ := store.Pending(); len(pending) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the job to be removed once done")
+		}
+	}
+}
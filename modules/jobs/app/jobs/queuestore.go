@@ -0,0 +1,199 @@
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/pyanfield/revel"
+	"github.com/pyanfield/revel/modules/db/app"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A store saves the queued jobs until they are done.
+type Store interface {
+	// Save a new job.
+	Add(job *QueuedJob) error
+	// Save the changes to a job (its Attempt and RunAt).
+	Update(job *QueuedJob) error
+	// Remove a job, when it is done or given up.
+	Remove(job *QueuedJob) error
+	// Return all the jobs saved, including those that were running.
+	Pending() ([]*QueuedJob, error)
+}
+
+// The store of the queue.  Unless the app sets it before startup, it is chosen
+// in app.conf:
+//
+//   jobs.queue = fs                # JSON files, in jobs.queue.path (default: queue)
+//   jobs.queue.path = /var/lib/myapp/queue
+//
+//   jobs.queue = db                # The database of modules/db (db.driver, db.spec),
+//   jobs.queue.table = revel_jobs  # in this table, created if needed.
+//
+// The queue is meant for a single instance of the app: jobs are not claimed,
+// so instances sharing a store would each run all of its jobs.
+var QueueStore Store
+
+// Return the store configured in app.conf.
+func configQueueStore() (Store, error) {
+	switch kind := revel.Config.StringDefault("jobs.queue", "fs"); kind {
+	case "fs":
+		path := revel.Config.StringDefault("jobs.queue.path", "queue")
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(revel.BasePath, path)
+		}
+		return NewFileStore(path), nil
+	case "db":
+		table := revel.Config.StringDefault("jobs.queue.table", "revel_jobs")
+		if db.Db != nil {
+			return NewSqlStore(db.Db, db.Driver, table)
+		}
+
+		// The db plugin usually starts after this one, so open a connection
+		// of the queue's own, rather than waiting for it.
+		driver, spec := revel.Config.StringDefault("db.driver", ""), revel.Config.StringDefault("db.spec", "")
+		if driver == "" || spec == "" {
+			return nil, fmt.Errorf("jobs.queue = db needs db.driver and db.spec")
+		}
+		queueDb, err := sql.Open(driver, spec)
+		if err != nil {
+			return nil, err
+		}
+		return NewSqlStore(queueDb, driver, table)
+	default:
+		return nil, fmt.Errorf("unknown jobs.queue %s", kind)
+	}
+}
+
+// A store of JSON files, one for each job, in a directory.
+type FileStore struct {
+	path string
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path}
+}
+
+func (s *FileStore) Add(job *QueuedJob) error {
+	if err := os.MkdirAll(s.path, 0755); err != nil {
+		return err
+	}
+	return s.Update(job)
+}
+
+func (s *FileStore) Update(job *QueuedJob) error {
+	jobBytes, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	// Write a temporary file and rename it, so the file is never half written.
+	tempName := s.fileName(job) + ".tmp"
+	if err = ioutil.WriteFile(tempName, jobBytes, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempName, s.fileName(job))
+}
+
+func (s *FileStore) Remove(job *QueuedJob) error {
+	err := os.Remove(s.fileName(job))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) Pending() ([]*QueuedJob, error) {
+	fileNames, err := filepath.Glob(filepath.Join(s.path, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var jobs []*QueuedJob
+	for _, fileName := range fileNames {
+		jobBytes, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		job := &QueuedJob{}
+		if err = json.Unmarshal(jobBytes, job); err != nil {
+			revel.ERROR.Printf("Skipping queued job %s: %s", fileName, err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (s *FileStore) fileName(job *QueuedJob) string {
+	return filepath.Join(s.path, job.Id+".json")
+}
+
+// A store of the jobs in a database table, for a single instance of the app.
+type SqlStore struct {
+	db       *sql.DB
+	table    string
+	postgres bool // Whether to use $1 placeholders, rather than ?
+}
+
+// Return a store of the jobs in the given table, which is created if needed.
+func NewSqlStore(db *sql.DB, driver, table string) (*SqlStore, error) {
+	s := &SqlStore{db, table, driver == "postgres"}
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + table + ` (
+		id VARCHAR(64) PRIMARY KEY,
+		type VARCHAR(255) NOT NULL,
+		payload TEXT NOT NULL,
+		run_at BIGINT NOT NULL,
+		attempt INTEGER NOT NULL
+	)`)
+	return s, err
+}
+
+func (s *SqlStore) Add(job *QueuedJob) error {
+	return s.exec(`INSERT INTO `+s.table+` (id, type, payload, run_at, attempt) VALUES (?, ?, ?, ?, ?)`,
+		job.Id, job.Type, string(job.Payload), job.RunAt.UnixNano(), job.Attempt)
+}
+
+func (s *SqlStore) Update(job *QueuedJob) error {
+	return s.exec(`UPDATE `+s.table+` SET run_at = ?, attempt = ? WHERE id = ?`,
+		job.RunAt.UnixNano(), job.Attempt, job.Id)
+}
+
+func (s *SqlStore) Remove(job *QueuedJob) error {
+	return s.exec(`DELETE FROM `+s.table+` WHERE id = ?`, job.Id)
+}
+
+func (s *SqlStore) Pending() ([]*QueuedJob, error) {
+	rows, err := s.db.Query(`SELECT id, type, payload, run_at, attempt FROM ` + s.table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*QueuedJob
+	for rows.Next() {
+		var (
+			job     = &QueuedJob{}
+			payload string
+			runAt   int64
+		)
+		if err = rows.Scan(&job.Id, &job.Type, &payload, &runAt, &job.Attempt); err != nil {
+			return nil, err
+		}
+		job.Payload, job.RunAt = json.RawMessage(payload), time.Unix(0, runAt)
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *SqlStore) exec(query string, args ...interface{}) error {
+	if s.postgres {
+		for i := range args {
+			query = strings.Replace(query, "?", fmt.Sprintf("$%d", i+1), 1)
+		}
+	}
+	_, err := s.db.Exec(query, args...)
+	return err
+}
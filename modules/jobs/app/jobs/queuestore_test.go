@@ -0,0 +1,152 @@
+package jobs
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := NewFileStore(filepath.Join(dir, "queue"))
+
+	job := &QueuedJob{Id: "1-1", Type: "greet", Payload: json.RawMessage(`{"Name":"Ana"}`), RunAt: time.Unix(1381337733, 0)}
+	if err = store.Add(job); err != nil {
+		t.Fatal(err)
+	}
+	job.Attempt = 1
+	if err = store.Update(job); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "queue", "2-1.json"), []byte("not json"), 0644)
+
+	pending, err := store.Pending()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("Expected the job to be pending, skipping the unreadable one, got %v (%v)", pending, err)
+	}
+	if saved := pending[0]; saved.Id != job.Id || saved.Type != job.Type || string(saved.Payload) != string(job.Payload) ||
+		!saved.RunAt.Equal(job.RunAt) || saved.Attempt != 1 {
+		t.Errorf("Expected the update to be saved, got %+v", pending[0])
+	}
+	if tempFiles, _ := filepath.Glob(filepath.Join(dir, "queue", "*.tmp")); len(tempFiles) > 0 {
+		t.Errorf("Expected no temporary files, got %v", tempFiles)
+	}
+
+	if err = store.Remove(job); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Remove(job); err != nil {
+		t.Errorf("Expected removing a removed job to succeed, got %s", err)
+	}
+	if pending, _ = store.Pending(); len(pending) != 0 {
+		t.Errorf("Expected no pending jobs, got %v", pending)
+	}
+}
+
+// A database driver that records the statements it is given, and returns
+// the rows it is told to for queries.
+type recordingDriver struct {
+	statements []string
+	args       [][]driver.Value
+	rows       [][]driver.Value
+}
+
+var testDriver = &recordingDriver{}
+
+func init() {
+	sql.Register("jobs-test", testDriver)
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) { return recordingConn{d}, nil }
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) { return recordingStmt{c.d, query}, nil }
+func (c recordingConn) Close() error                              { return nil }
+func (c recordingConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.statements = append(s.d.statements, s.query)
+	s.d.args = append(s.d.args, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.statements = append(s.d.statements, s.query)
+	return &recordingRows{rows: s.d.rows}, nil
+}
+
+type recordingRows struct{ rows [][]driver.Value }
+
+func (r *recordingRows) Columns() []string {
+	return []string{"id", "type", "payload", "run_at", "attempt"}
+}
+func (r *recordingRows) Close() error { return nil }
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestSqlStore(t *testing.T) {
+	var sqlTests = []struct {
+		driver, update string
+	}{
+		{"mysql", "UPDATE jobs SET run_at = ?, attempt = ? WHERE id = ?"},
+		{"postgres", "UPDATE jobs SET run_at = $1, attempt = $2 WHERE id = $3"},
+	}
+	for _, test := range sqlTests {
+		*testDriver = recordingDriver{}
+		sqlDb, _ := sql.Open("jobs-test", "")
+		store, err := NewSqlStore(sqlDb, test.driver, "jobs")
+		if err != nil || !strings.HasPrefix(testDriver.statements[0], "CREATE TABLE IF NOT EXISTS jobs") {
+			t.Fatalf("%s: expected the table to be created, got %v (%v)", test.driver, testDriver.statements, err)
+		}
+
+		job := &QueuedJob{Id: "1-1", Type: "greet", Payload: json.RawMessage(`{}`), RunAt: time.Unix(0, 42), Attempt: 2}
+		store.Add(job)
+		store.Update(job)
+		store.Remove(job)
+		if testDriver.statements[2] != test.update {
+			t.Errorf("%s: expected %s, got %s", test.driver, test.update, testDriver.statements[2])
+		}
+		if !reflect.DeepEqual(testDriver.args[1], []driver.Value{"1-1", "greet", "{}", int64(42), int64(2)}) ||
+			!reflect.DeepEqual(testDriver.args[3], []driver.Value{"1-1"}) {
+			t.Errorf("%s: unexpected args %v", test.driver, testDriver.args)
+		}
+		sqlDb.Close()
+	}
+
+	*testDriver = recordingDriver{rows: [][]driver.Value{{"1-1", "greet", "{}", int64(42), int64(2)}}}
+	sqlDb, _ := sql.Open("jobs-test", "")
+	defer sqlDb.Close()
+	pending, err := (&SqlStore{db: sqlDb, table: "jobs"}).Pending()
+	expected := QueuedJob{Id: "1-1", Type: "greet", Payload: json.RawMessage(`{}`), RunAt: time.Unix(0, 42), Attempt: 2}
+	if err != nil || len(pending) != 1 || !reflect.DeepEqual(*pending[0], expected) {
+		t.Errorf("Expected %+v, got %v (%v)", expected, pending, err)
+	}
+}
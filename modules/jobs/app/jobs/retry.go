@@ -0,0 +1,150 @@
+package jobs
+
+import (
+	"github.com/pyanfield/cron"
+	"github.com/pyanfield/revel"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// A retry policy says how often, and after how long, a failed job is retried.
+// The delay before each retry doubles, from Backoff up to MaxBackoff, and a
+// random part of it (Jitter) is taken off so that jobs failing together are
+// not all retried together.
+type RetryPolicy struct {
+	MaxAttempts int           // The most runs of the job, including the first.
+	Backoff     time.Duration // The delay before the first retry.
+	MaxBackoff  time.Duration // The longest delay, or 0 for no limit.
+	Jitter      float64       // The fraction of the delay that is random, from 0 to 1.
+}
+
+// A policy of 5 attempts in about a minute.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	Backoff:     2 * time.Second,
+	MaxBackoff:  time.Minute,
+	Jitter:      0.2,
+}
+
+// Return the delay before the retry after the given attempt.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff == 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		delay -= time.Duration(p.Jitter * rand.Float64() * float64(delay))
+	}
+	return delay
+}
+
+// A job with a retry policy.
+type retryJob struct {
+	cron.Job
+	policy RetryPolicy
+}
+
+// Retry the job as the policy allows when it fails: when it panics, returns an
+// error (see ErrorJob) or times out.  Without a policy, failed jobs are not
+// retried.
+//
+// Ad-hoc jobs are retried until they succeed or run out of attempts.  So are
+// scheduled ones, independently of their next scheduled runs.
+//
+// For example:
+//    jobs.In(time.Minute, jobs.WithRetry(jobs.DefaultRetryPolicy, SendReminder{booking}))
+func WithRetry(policy RetryPolicy, job cron.Job) cron.Job {
+	return retryJob{job, policy}
+}
+
+// A retry waiting for its delay, with the failure it follows.
+type pendingRetry struct {
+	timer   *time.Timer
+	failure Failure
+}
+
+var (
+	retriesMu      sync.Mutex
+	pendingRetries = map[*pendingRetry]bool{}
+)
+
+// Remove the retry, returning false if it was dropped already.
+func takeRetry(retry *pendingRetry) bool {
+	retriesMu.Lock()
+	defer retriesMu.Unlock()
+	pending := pendingRetries[retry]
+	delete(pendingRetries, retry)
+	return pending
+}
+
+// Give up the retries that are waiting, as the app is stopping.
+func dropRetries() {
+	retriesMu.Lock()
+	dropped := pendingRetries
+	pendingRetries = map[*pendingRetry]bool{}
+	retriesMu.Unlock()
+
+	for retry := range dropped {
+		retry.timer.Stop()
+		dropRetry(retry.failure)
+	}
+}
+
+// Give up the job instead of retrying it after the failure, as the app is
+// stopping.  (Only the give-up hooks are called, as the failure hooks were
+// called for the failure already)
+func dropRetry(failure Failure) {
+	revel.WARN.Printf("Giving up job %s after attempt %d: the app is stopping", failure.Job.Name, failure.Attempt)
+	failure.GaveUp = true
+	for _, hook := range giveUpHooks {
+		callFailureHook(hook, failure)
+	}
+}
+
+// A failed attempt to run a job.
+type Failure struct {
+	Job     *Job
+	Attempt int   // The attempt that failed, from 1.
+	Err     error // Why it failed.
+	GaveUp  bool  // Whether it was the last attempt, or the app is stopping.
+}
+
+var failureHooks, giveUpHooks []func(Failure)
+
+// Register a function to call when a job fails, e.g. to alert someone.  It is
+// called for each failed attempt, in the goroutine of the job.
+func OnFailure(hook func(Failure)) {
+	failureHooks = append(failureHooks, hook)
+}
+
+// Register a function to call when a job has failed for the last time, after
+// its retries, e.g. to save its work in a dead-letter list.  It is also called
+// for the retries that are waiting when the app stops, with the last failure.
+func OnGiveUp(hook func(Failure)) {
+	giveUpHooks = append(giveUpHooks, hook)
+}
+
+func notifyFailure(failure Failure) {
+	for _, hook := range failureHooks {
+		callFailureHook(hook, failure)
+	}
+	if failure.GaveUp {
+		for _, hook := range giveUpHooks {
+			callFailureHook(hook, failure)
+		}
+	}
+}
+
+// Call the hook, printing a panic rather than letting it take down the process.
+func callFailureHook(hook func(Failure), failure Failure) {
+	defer func() {
+		if err := recover(); err != nil {
+			revel.ERROR.Printf("Failure hook of job %s panicked: %v", failure.Job.Name, err)
+		}
+	}()
+	hook(failure)
+}
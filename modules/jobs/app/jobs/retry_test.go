@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, expected := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 5 * time.Second,
+		9: 5 * time.Second,
+	} {
+		if actual := policy.delay(attempt); actual != expected {
+			t.Errorf("Attempt %d: expected %s, got %s", attempt, expected, actual)
+		}
+	}
+
+	policy.MaxBackoff = 0
+	if actual := policy.delay(5); actual != 16*time.Second {
+		t.Errorf("Expected no limit without MaxBackoff, got %s", actual)
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if actual := policy.delay(2); actual <= time.Second || actual > 2*time.Second {
+			t.Fatalf("Expected a delay between 1s and 2s with jitter, got %s", actual)
+		}
+	}
+}
+
+func TestNotifyFailure(t *testing.T) {
+	setupJobs(t, "")
+	var failures, giveUps []Failure
+	OnFailure(func(f Failure) { panic("hook panicked") })
+	OnFailure(func(f Failure) { failures = append(failures, f) })
+	OnGiveUp(func(f Failure) { giveUps = append(giveUps, f) })
+
+	j := New(Func(func() {}))
+	notifyFailure(Failure{Job: j, Attempt: 1})
+	notifyFailure(Failure{Job: j, Attempt: 2, GaveUp: true})
+	if len(failures) != 2 {
+		t.Errorf("Expected the failure hooks to be called for each failure, despite a panic, got %v", failures)
+	}
+	if len(giveUps) != 1 || giveUps[0].Attempt != 2 {
+		t.Errorf("Expected the give-up hooks to be called for the last failure, got %v", giveUps)
+	}
+}
+
+func TestCallFailureHookRecoversPanics(t *testing.T) {
+	called := false
+	callFailureHook(func(f Failure) {
+		called = true
+		panic("hook panicked")
+	}, Failure{Job: New(Func(func() {}))})
+	if !called {
+		t.Error("Expected the hook to be called")
+	}
+}
+
+func TestRetryWaitsForTheAbandonedRun(t *testing.T) {
+	setupJobs(t, "")
+	selfConcurrent = true
+	inner := newBlockingJob()
+	j := New(WithTimeout(20*time.Millisecond, WithRetry(RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}, inner)))
+
+	var mu sync.Mutex
+	var retried []Failure
+	OnFailure(func(f Failure) {
+		mu.Lock()
+		retried = append(retried, f)
+		mu.Unlock()
+	})
+	j.Run()
+	time.Sleep(30 * time.Millisecond)
+	if inner.Runs() != 1 {
+		t.Errorf("Expected the retry to wait for the abandoned run, got %d runs", inner.Runs())
+	}
+
+	close(inner.release)
+	for deadline := time.Now().Add(time.Second); inner.Runs() != 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the job to be retried once the abandoned run returned")
+		}
+	}
+	for deadline := time.Now().Add(time.Second); j.Successes() != 1; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the retry to return")
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(retried) != 1 {
+		t.Errorf("Expected only the first attempt to fail, got %v", retried)
+	}
+}
+
+func TestRetriesAreGivenUpOnStop(t *testing.T) {
+	setupJobs(t, "")
+	MainCron.Start()
+	jobErr := errors.New("failed")
+	runs := 0
+	j := New(WithRetry(RetryPolicy{MaxAttempts: 3, Backoff: time.Hour}, ErrorFunc(func(ctx context.Context) error {
+		runs++
+		return jobErr
+	})))
+
+	var failures, giveUps []Failure
+	OnFailure(func(f Failure) { failures = append(failures, f) })
+	OnGiveUp(func(f Failure) { giveUps = append(giveUps, f) })
+	j.Run()
+	if len(pendingRetries) != 1 {
+		t.Fatalf("Expected a pending retry, got %d", len(pendingRetries))
+	}
+
+	JobsPlugin{}.OnAppStop()
+	if len(pendingRetries) != 0 {
+		t.Errorf("Expected the pending retries to be dropped, got %d", len(pendingRetries))
+	}
+	if runs != 1 || len(failures) != 1 {
+		t.Errorf("Expected 1 failed run, got %d runs and failures %v", runs, failures)
+	}
+	if len(giveUps) != 1 || giveUps[0].Attempt != 1 || giveUps[0].Err != jobErr || !giveUps[0].GaveUp {
+		t.Errorf("Expected the give-up hooks to be called with the last failure, got %v", giveUps)
+	}
+}
@@ -1,9 +1,15 @@
 package controllers
 
 import (
+	"fmt"
 	"github.com/pyanfield/revel"
+	"net/http"
 	"os"
+	"path"
 	fpath "path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Static struct {
@@ -15,8 +21,12 @@ type Static struct {
 // application directory. The filepath may either be just a file or an
 // additional filepath to search for the given file. This response may return
 // the following responses in the event of an error or invalid request;
-//   403(Forbidden): If the prefix filepath combination results in a directory.
-//   404(Not found): If the prefix and filepath combination results in a non-existent file.
+//   403(Forbidden): If the prefix filepath combination results in a directory
+//     (without an index.html, or with static.index=false).
+//   404(Not found): If the prefix and filepath combination results in a non-existent file,
+//     or a file outside of the prefix.
+//   304(Not modified): If the file matches the request's If-None-Match or If-Modified-Since.
+//   206(Partial content): If the request asks for a Range of the file.
 //   500(Internal Server Error): There are a few edge cases that would likely indicate some configuration error outside of revel.
 //
 // Note that when defining routes in routes/conf the parameters must not have
@@ -47,24 +57,50 @@ func (c Static) Serve(prefix, filepath string) revel.Result {
 		basePath = revel.BasePath
 	}
 
-	fname := fpath.Join(basePath, fpath.FromSlash(prefix), fpath.FromSlash(filepath))
+	basePathPrefix := fpath.Join(basePath, fpath.FromSlash(prefix))
+	fname := fpath.Join(basePathPrefix, fpath.FromSlash(filepath))
 
-	finfo, err := os.Stat(fname)
+	// Disallow reading files outside of the prefix (e.g. by way of "..").
+	if fname != basePathPrefix && !strings.HasPrefix(fname, basePathPrefix+string(fpath.Separator)) {
+		revel.WARN.Printf("Attempted to read file outside of %s: %s", basePathPrefix, fname)
+		return c.NotFound("File not found")
+	}
+
+	// Fingerprinted assets never change, so they may be cached forever.
+	// (See revel.AssetManager)
+	if revel.MainAssets != nil {
+		if file, fingerprinted, err := revel.MainAssets.Open(fname); err == nil {
+			setStaticCacheControl(c.Response, path.Join(prefix, filepath))
+			if fingerprinted {
+				c.Response.Out.Header().Set("Cache-Control", revel.AssetCacheControl)
+				c.Response.Out.Header().Set("Expires",
+					time.Now().AddDate(1, 0, 0).UTC().Format(http.TimeFormat))
+			}
+			info, _ := file.Stat()
+			return c.serveContent(file, info, info.Name())
+		}
+	}
+
+	// The file may be embedded in the binary (see "revel build -embed").
+	finfo, err := revel.StatFile(fname)
 
 	if err == nil {
 		if finfo.Mode().IsDir() {
+			// Serve the directory's index.html, if enabled.
+			if revel.Config.BoolDefault("static.index", false) {
+				if indexInfo, err := revel.StatFile(fpath.Join(fname, "index.html")); err == nil && !indexInfo.IsDir() {
+					setStaticCacheControl(c.Response, path.Join(prefix, filepath))
+					return c.serveFile(fpath.Join(fname, "index.html"), indexInfo)
+				}
+			}
 			revel.WARN.Printf("Attempted directory listing of %s", fname)
 			return c.Forbidden("Directory listing not allowed")
 		}
-		file, err := os.Open(fname)
-		if os.IsNotExist(err) {
-			revel.WARN.Printf("File not found (%s): %s ", fname, err)
-			return c.NotFound("File not found")
-		} else if err != nil {
-			revel.WARN.Printf("Problem opening file (%s): %s ", fname, err)
-			return c.RenderError(err)
-		}
-		return c.RenderFile(file, "")
+		setStaticCacheControl(c.Response, path.Join(prefix, filepath))
+		return c.serveFile(fname, finfo)
+	} else if os.IsNotExist(err) {
+		revel.WARN.Printf("File not found (%s): %s ", fname, err)
+		return c.NotFound("File not found")
 	} else {
 		revel.ERROR.Printf("Error trying to get fileinfo for '%s': %s", fname, err)
 	}
@@ -73,6 +109,85 @@ func (c Static) Serve(prefix, filepath string) revel.Result {
 
 }
 
+// Serve the given file, or its precompressed sibling (with the extension .gz)
+// if there is one and the client accepts gzip encoding.
+func (c Static) serveFile(fname string, finfo os.FileInfo) revel.Result {
+	if gzInfo, err := revel.StatFile(fname + ".gz"); err == nil && !gzInfo.IsDir() {
+		c.Response.Out.Header().Add("Vary", "Accept-Encoding")
+		if acceptsGzip(c.Request) {
+			if file, err := revel.OpenFile(fname + ".gz"); err == nil {
+				c.Response.Out.Header().Set("Content-Encoding", "gzip")
+				return c.serveContent(file, gzInfo, finfo.Name())
+			}
+		}
+	}
+
+	file, err := revel.OpenFile(fname)
+	if os.IsNotExist(err) {
+		revel.WARN.Printf("File not found (%s): %s ", fname, err)
+		return c.NotFound("File not found")
+	} else if err != nil {
+		revel.WARN.Printf("Problem opening file (%s): %s ", fname, err)
+		return c.RenderError(err)
+	}
+	return c.serveContent(file, finfo, finfo.Name())
+}
+
+// Serve the content of the file with an ETag and Last-Modified date, which
+// (by way of http.ServeContent) answer Range and conditional requests, with
+// 206 (Partial Content) and 304 (Not Modified) responses.
+// The name determines the Content-Type.
+func (c Static) serveContent(file http.File, finfo os.FileInfo, name string) revel.Result {
+	c.Response.Out.Header().Set("ETag",
+		fmt.Sprintf(`"%x-%x"`, finfo.ModTime().UnixNano(), finfo.Size()))
+	return &revel.BinaryResult{
+		ReadSeeker: file,
+		Name:       name,
+		Length:     finfo.Size(),
+		ModTime:    finfo.ModTime(),
+	}
+}
+
+// Set the Cache-Control configured for the longest prefix of the given path
+// (the prefix and file path as given in the route), e.g.
+//   static.cache.public = public, max-age=3600
+//   static.cache.public/img = public, max-age=86400
+func setStaticCacheControl(resp *revel.Response, filepath string) {
+	var longestPrefix, cacheControl string
+	for _, key := range revel.Config.Options("static.cache.") {
+		prefix := strings.Trim(key[len("static.cache."):], "/")
+		if (filepath == prefix || strings.HasPrefix(filepath, prefix+"/")) &&
+			len(prefix) >= len(longestPrefix) {
+			longestPrefix = prefix
+			cacheControl = revel.Config.StringDefault(key, "")
+		}
+	}
+	if cacheControl != "" {
+		resp.Out.Header().Set("Cache-Control", cacheControl)
+	}
+}
+
+// Return true if the request accepts gzip content encoding.
+func acceptsGzip(req *revel.Request) bool {
+	for _, encoding := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(encoding, ";")
+		if strings.TrimSpace(parts[0]) != "gzip" {
+			continue
+		}
+		// A quality of 0 means "not acceptable".
+		if len(parts) > 1 {
+			param := strings.TrimSpace(parts[1])
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
 // This method allows modules to serve binary files. The parameters are the same
 // as Static.Serve with the additional module name pre-pended to the list of
 // arguments.
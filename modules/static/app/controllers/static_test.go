@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"github.com/pyanfield/revel"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Create a directory with public files, a sibling directory sharing the
+// prefix, and a file outside of both, and load an empty app.conf.
+func setupStaticDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "revel-static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"conf/app.conf":          "",
+		"public/hello.txt":       "hello",
+		"public/hello.txt.gz":    "gzipped hello",
+		"public/img/logo.png":    "png",
+		"public-secret/key.txt":  "secret",
+		"secret.txt":             "secret",
+		"public/%2e%2e/note.txt": "not a parent",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte(content), 0644)
+	}
+
+	revel.BasePath = dir
+	revel.ConfPaths = []string{filepath.Join(dir, "conf")}
+	if revel.Config, err = revel.LoadConfig("app.conf"); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func newStaticController(acceptEncoding string) Static {
+	req, _ := http.NewRequest("GET", "/public/hello.txt", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	return Static{revel.NewController(revel.NewRequest(req), revel.NewResponse(httptest.NewRecorder()),
+		&revel.ControllerType{Type: reflect.TypeOf(Static{})})}
+}
+
+func TestServeRejectsPathsOutsideOfPrefix(t *testing.T) {
+	dir := setupStaticDir(t)
+	defer os.RemoveAll(dir)
+
+	for _, path := range []string{
+		"../secret.txt",
+		"img/../../secret.txt",
+		"../public-secret/key.txt",
+	} {
+		c := newStaticController("")
+		if _, ok := c.Serve("public", path).(revel.ErrorResult); !ok || c.Response.Status != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, c.Response.Status)
+		}
+	}
+
+	// An encoded ".." is just a name, within the prefix.
+	c := newStaticController("")
+	if _, ok := c.Serve("public", "%2e%2e/note.txt").(*revel.BinaryResult); !ok {
+		t.Errorf("Expected the file named %%2e%%2e to be served, got %d", c.Response.Status)
+	}
+}
+
+func TestServeNotFound(t *testing.T) {
+	dir := setupStaticDir(t)
+	defer os.RemoveAll(dir)
+
+	c := newStaticController("")
+	if _, ok := c.Serve("public", "missing.txt").(revel.ErrorResult); !ok || c.Response.Status != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", c.Response.Status)
+	}
+
+	c = newStaticController("")
+	if _, ok := c.Serve("public", "img").(revel.ErrorResult); !ok || c.Response.Status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a directory, got %d", c.Response.Status)
+	}
+}
+
+func TestServeGzip(t *testing.T) {
+	dir := setupStaticDir(t)
+	defer os.RemoveAll(dir)
+
+	var gzipTests = []struct {
+		acceptEncoding, contentEncoding string
+		length                          int64
+	}{
+		{"gzip, deflate", "gzip", int64(len("gzipped hello"))},
+		{"deflate, gzip;q=0", "", int64(len("hello"))},
+		{"", "", int64(len("hello"))},
+	}
+	for _, test := range gzipTests {
+		c := newStaticController(test.acceptEncoding)
+		result, ok := c.Serve("public", "hello.txt").(*revel.BinaryResult)
+		if !ok {
+			t.Fatalf("%q: expected the file to be served", test.acceptEncoding)
+		}
+		header := c.Response.Out.Header()
+		if header.Get("Content-Encoding") != test.contentEncoding || result.Length != test.length {
+			t.Errorf("%q: expected encoding %q and length %d, got %q and %d", test.acceptEncoding,
+				test.contentEncoding, test.length, header.Get("Content-Encoding"), result.Length)
+		}
+		if header.Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%q: expected Vary: Accept-Encoding", test.acceptEncoding)
+		}
+		if result.Name != "hello.txt" {
+			t.Errorf("%q: expected the name hello.txt, got %s", test.acceptEncoding, result.Name)
+		}
+	}
+}
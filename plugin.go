@@ -16,6 +16,13 @@ type Plugin interface {
 	Finally(c *Controller)
 }
 
+// Plugins may also implement StoppingPlugin, to be told when the app stops:
+// on interrupt or terminate, with app.handle_signals, or when the app calls
+// StopApp.
+type StoppingPlugin interface {
+	OnAppStop()
+}
+
 // To define a Plugin of your own, declare a type that embeds revel.EmptyPlugin, 
 // and override just the methods that you want. Then register it with revel.RegisterPlugin.
 //
@@ -82,3 +89,12 @@ func (plugins PluginCollection) Finally(c *Controller) {
 		p.Finally(c)
 	}
 }
+
+// Stop the plugins that implement StoppingPlugin.
+func (plugins PluginCollection) OnAppStop() {
+	for _, p := range plugins {
+		if stopping, ok := p.(StoppingPlugin); ok {
+			stopping.OnAppStop()
+		}
+	}
+}
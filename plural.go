@@ -0,0 +1,167 @@
+package revel
+
+import (
+	"math"
+	"strings"
+)
+
+// The CLDR plural categories.
+const (
+	PluralZero  = "zero"
+	PluralOne   = "one"
+	PluralTwo   = "two"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+// A plural rule returns the plural category of a number, given its absolute
+// value and whether it is an integer.
+type PluralRule func(n float64, integer bool) string
+
+// The plural rules of the languages, after the CLDR rules for integers.
+// Languages that are not listed use the English rule.
+var PluralRules = map[string]PluralRule{}
+
+func init() {
+	for _, language := range []string{"ja", "ko", "zh", "vi", "th", "id", "ms"} {
+		PluralRules[language] = pluralRuleOther
+	}
+	for _, language := range []string{"fr", "pt-br"} {
+		PluralRules[language] = pluralRuleFrench
+	}
+	for _, language := range []string{"ru", "uk", "be"} {
+		PluralRules[language] = pluralRuleRussian
+	}
+	for _, language := range []string{"cs", "sk"} {
+		PluralRules[language] = pluralRuleCzech
+	}
+	PluralRules["pl"] = pluralRulePolish
+	PluralRules["ar"] = pluralRuleArabic
+	PluralRules["he"] = pluralRuleHebrew
+	PluralRules["lv"] = pluralRuleLatvian
+}
+
+// Return the plural category of the number for the locale, e.g. "one" for 1
+// in English, or "few" for 3 in Russian.
+func PluralCategory(locale string, count interface{}) string {
+	n, ok := toFloat(count)
+	if !ok {
+		return PluralOther
+	}
+	n = math.Abs(n)
+	integer := n == math.Floor(n)
+
+	language, region := parseLocale(locale)
+	language = strings.ToLower(language)
+	rule, found := PluralRules[strings.ToLower(language+"-"+region)]
+	if !found {
+		if rule, found = PluralRules[language]; !found {
+			rule = pluralRuleEnglish
+		}
+	}
+	return rule(n, integer)
+}
+
+func pluralRuleOther(n float64, integer bool) string {
+	return PluralOther
+}
+
+// en, de, nl, es, it, ...
+func pluralRuleEnglish(n float64, integer bool) string {
+	if integer && n == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralRuleFrench(n float64, integer bool) string {
+	if n < 2 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralRuleRussian(n float64, integer bool) string {
+	if !integer {
+		return PluralOther
+	}
+	mod10, mod100 := math.Mod(n, 10), math.Mod(n, 100)
+	switch {
+	case mod10 == 1 && mod100 != 11:
+		return PluralOne
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return PluralFew
+	}
+	return PluralMany
+}
+
+func pluralRulePolish(n float64, integer bool) string {
+	if !integer {
+		return PluralOther
+	}
+	mod10, mod100 := math.Mod(n, 10), math.Mod(n, 100)
+	switch {
+	case n == 1:
+		return PluralOne
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return PluralFew
+	}
+	return PluralMany
+}
+
+func pluralRuleCzech(n float64, integer bool) string {
+	switch {
+	case !integer:
+		return PluralMany
+	case n == 1:
+		return PluralOne
+	case n >= 2 && n <= 4:
+		return PluralFew
+	}
+	return PluralOther
+}
+
+func pluralRuleArabic(n float64, integer bool) string {
+	if !integer {
+		return PluralOther
+	}
+	mod100 := math.Mod(n, 100)
+	switch {
+	case n == 0:
+		return PluralZero
+	case n == 1:
+		return PluralOne
+	case n == 2:
+		return PluralTwo
+	case mod100 >= 3 && mod100 <= 10:
+		return PluralFew
+	case mod100 >= 11:
+		return PluralMany
+	}
+	return PluralOther
+}
+
+func pluralRuleHebrew(n float64, integer bool) string {
+	switch {
+	case integer && n == 1:
+		return PluralOne
+	case integer && n == 2:
+		return PluralTwo
+	}
+	return PluralOther
+}
+
+func pluralRuleLatvian(n float64, integer bool) string {
+	if !integer {
+		return PluralOther
+	}
+	mod10, mod100 := math.Mod(n, 10), math.Mod(n, 100)
+	switch {
+	case mod10 == 0 || (mod100 >= 11 && mod100 <= 19):
+		return PluralZero
+	case mod10 == 1 && mod100 != 11:
+		return PluralOne
+	}
+	return PluralOther
+}
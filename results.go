@@ -129,9 +129,17 @@ func (r PlaintextErrorResult) Apply(req *Request, resp *Response) {
 type RenderTemplateResult struct {
 	Template   Template
 	RenderArgs map[string]interface{}
+	// If true, the template is streamed to the client as it renders, in chunks,
+	// rather than staged in a buffer.  (See Controller.RenderTemplateStream)
+	Stream bool
 }
 
 func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
+	if r.Stream {
+		streamTemplate(r.Template, r.RenderArgs, req, resp)
+		return
+	}
+
 	// If "result staging" is on..
 	// Render the template into a temporary buffer, to see if there was an error
 	// rendering the template.  If not, then copy it into the response buffer.
@@ -172,19 +180,30 @@ func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
 			return
 		}
 
-		resp.WriteHeader(http.StatusOK, "text/html")
+		resp.WriteHeader(http.StatusOK, templateContentType(r.Template))
 		b.WriteTo(resp.Out)
 		return
 	}
 
 	// Else, write the status, render, and hope for the best.
-	resp.WriteHeader(http.StatusOK, "text/html")
+	resp.WriteHeader(http.StatusOK, templateContentType(r.Template))
 	err := r.Template.Render(resp.Out, r.RenderArgs)
 	if err != nil {
 		ERROR.Println("Failed to render template", r.Template.Name(), "\n", err)
 	}
 }
 
+// Return the content type of the template, by its extension, e.g.
+// "application/json" for Hotels/Show.json.  HTML is the default.
+func templateContentType(tmpl Template) string {
+	if mimeConfig != nil {
+		if contentType := ContentTypeByFilename(tmpl.Name()); contentType != DefaultFileContentType {
+			return contentType
+		}
+	}
+	return "text/html"
+}
+
 type RenderHtmlResult struct {
 	html string
 }
@@ -216,6 +235,26 @@ func (r RenderJsonResult) Apply(req *Request, resp *Response) {
 	resp.Out.Write(b)
 }
 
+// The HTTP status code for validation failures (RFC 4918).
+const StatusUnprocessableEntity = 422
+
+// This result reports validation errors to API clients, e.g.
+//   {"errors": [{"key": "user.Name", "message": "Required", "rule": "Required"}]}
+type ValidationErrorsResult struct {
+	Errors []*ValidationError
+}
+
+func (r *ValidationErrorsResult) Apply(req *Request, resp *Response) {
+	if resp.Status == 0 {
+		resp.Status = StatusUnprocessableEntity
+	}
+	validationErrors := r.Errors
+	if validationErrors == nil {
+		validationErrors = []*ValidationError{}
+	}
+	RenderJsonResult{map[string]interface{}{"errors": validationErrors}}.Apply(req, resp)
+}
+
 type RenderXmlResult struct {
 	obj interface{}
 }
@@ -263,11 +302,14 @@ type BinaryResult struct {
 }
 
 func (r *BinaryResult) Apply(req *Request, resp *Response) {
-	disposition := string(r.Delivery)
-	if r.Name != "" {
-		disposition += fmt.Sprintf("; filename=%s;", r.Name)
+	// Files served inline without a disposition (e.g. static files) need no header.
+	if r.Delivery != "" {
+		disposition := string(r.Delivery)
+		if r.Name != "" {
+			disposition += fmt.Sprintf("; filename=%s;", r.Name)
+		}
+		resp.Out.Header().Set("Content-Disposition", disposition)
 	}
-	resp.Out.Header().Set("Content-Disposition", disposition)
 
 	http.ServeContent(resp.Out, req.Request, r.Name, r.ModTime, r.ReadSeeker)
 
@@ -298,6 +340,9 @@ func (r *RedirectToActionResult) Apply(req *Request, resp *Response) {
 		ErrorResult{Error: err}.Apply(req, resp)
 		return
 	}
+	if _, isUrl := r.val.(string); !isUrl {
+		url = localizeUrl(req.Locale, url)
+	}
 	resp.Out.Header().Set("Location", url)
 	resp.WriteHeader(http.StatusFound, "")
 }
@@ -86,7 +86,12 @@ func Init(mode, importPath, srcPath string) {
 
 	// If the SourcePath is not specified, find it using build.Import.
 	var revelSourcePath string // may be different from the app source path
-	if SourcePath == "" {
+	if SourcePath == "" && Embedded() {
+		// The app was built with its files embedded in the binary.
+		SourcePath = embeddedSourcePath
+		revelSourcePath = SourcePath
+
+	} else if SourcePath == "" {
 		//根据 importPath 得到revelSourcePath 和 SourcePath 的 root directory
 		// INFO.Println("importPath >>", importPath)
 		revelSourcePath, SourcePath = findSrcPaths(importPath)
@@ -233,6 +238,13 @@ func loadModules() {
 			continue
 		}
 
+		// Embedded modules are found below the source root, like the app.
+		if Embedded() {
+			addModule(key[len("module."):], moduleImportPath,
+				path.Join(SourcePath, filepath.FromSlash(moduleImportPath)))
+			continue
+		}
+
 		modPkg, err := build.Import(moduleImportPath, "", build.FindOnly)
 		if err != nil {
 			log.Fatalln("Failed to load module.  Import of", moduleImportPath, "failed:", err)
@@ -4,7 +4,6 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -176,7 +175,7 @@ func (router *Router) Route(req *http.Request) *RouteMatch {
 // Returns an error if a specified action could not be found.
 func (router *Router) Refresh() *Error {
 	// Get the routes file content.
-	contentBytes, err := ioutil.ReadFile(router.path)
+	contentBytes, err := ReadFile(router.path)
 	if err != nil {
 		return &Error{
 			Title:       "Failed to load routes file",
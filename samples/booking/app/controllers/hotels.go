@@ -96,7 +96,7 @@ func (c Hotels) SaveSettings(password, verifyPassword string) revel.Result {
 	models.ValidatePassword(c.Validation, password)
 	c.Validation.Required(verifyPassword).
 		Message("Please verify your password")
-	c.Validation.Required(verifyPassword == password).
+	c.Validation.EqualTo(verifyPassword, password).
 		Message("Your password doesn't match")
 	if c.Validation.HasErrors() {
 		c.Validation.Keep()
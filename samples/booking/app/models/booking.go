@@ -35,6 +35,8 @@ func (booking Booking) Validate(v *revel.Validation) {
 	v.Required(booking.Hotel)
 	v.Required(booking.CheckInDate)
 	v.Required(booking.CheckOutDate)
+	v.GreaterThan(booking.CheckOutDate, booking.CheckInDate).
+		Message("Check out date must be after check in date")
 
 	v.Match(booking.CardNumber, regexp.MustCompile(`\d{16}`)).
 		Message("Credit card number must be numeric and 16 digits")
@@ -6,6 +6,7 @@ import (
 )
 
 type Event struct {
+	Id        int    // Increasing, to tell where a reconnecting client left off
 	Type      string // "join", "leave", or "message"
 	User      string
 	Timestamp int    // Unix timestmap (secs)
@@ -24,7 +25,7 @@ func (s Subscription) Cancel() {
 }
 
 func newEvent(typ, user, msg string) Event {
-	return Event{typ, user, int(time.Now().Unix()), msg}
+	return Event{Type: typ, User: user, Timestamp: int(time.Now().Unix()), Text: msg}
 }
 
 func Subscribe() Subscription {
@@ -61,6 +62,7 @@ var (
 func chatroom() {
 	archive := list.New()
 	subscribers := list.New()
+	lastId := 0
 
 	for {
 		select {
@@ -74,6 +76,8 @@ func chatroom() {
 			ch <- Subscription{events, subscriber}
 
 		case event := <-publish:
+			lastId++
+			event.Id = lastId
 			for ch := subscribers.Front(); ch != nil; ch = ch.Next() {
 				ch.Value.(chan Event) <- event
 			}
@@ -28,6 +28,8 @@ func (c Application) EnterDemo(user, demo string) revel.Result {
 		return c.Redirect("/longpolling/room?user=%s", user)
 	case "websocket":
 		return c.Redirect("/websocket/room?user=%s", user)
+	case "eventsource":
+		return c.Redirect("/eventsource/room?user=%s", user)
 	}
 	return nil
 }
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"github.com/pyanfield/revel"
+	"github.com/pyanfield/revel/samples/chat/app/chatroom"
+	"strconv"
+)
+
+type EventSource struct {
+	*revel.Controller
+}
+
+func (c EventSource) Room(user string) revel.Result {
+	chatroom.Join(user)
+	return c.Render(user)
+}
+
+func (c EventSource) Say(user, message string) revel.Result {
+	chatroom.Say(user, message)
+	return nil
+}
+
+func (c EventSource) RoomEvents() revel.Result {
+	// A reconnecting client tells us the last event it received.
+	lastReceived, _ := strconv.Atoi(c.LastEventId())
+	subscription := chatroom.Subscribe()
+
+	// Forward the chat room events to the stream, until it closes.
+	events := make(chan revel.ServerEvent)
+	done := make(chan bool)
+	go func() {
+		defer close(events)
+		send := func(event chatroom.Event) bool {
+			select {
+			case events <- revel.ServerEvent{Id: strconv.Itoa(event.Id), Data: event}:
+				return true
+			case <-done:
+				return false
+			}
+		}
+
+		// Send down the archive, from where the client left off.
+		for _, event := range subscription.Archive {
+			if event.Id > lastReceived && !send(event) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case event := <-subscription.New:
+				if !send(event) {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// Unsubscribe when the client disconnects.
+	return c.RenderEventStream(events, func() {
+		close(done)
+		subscription.Cancel()
+	})
+}
+
+func (c EventSource) Leave(user string) revel.Result {
+	chatroom.Leave(user)
+	return c.Redirect(Application.Index)
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
 	"path"
 	"reflect"
+	"syscall"
 	"time"
 )
 
@@ -55,6 +58,14 @@ func handleInternal(w http.ResponseWriter, r *http.Request, ws *websocket.Conn)
 		}
 	}
 
+	// Strip the locale from the URL.  (See localeUrlConfigKey)
+	if localeUrlsEnabled() {
+		var locale string
+		if locale, r.URL.Path = splitLocalePath(r.URL.Path); locale != "" {
+			req.Locale = locale
+		}
+	}
+
 	// Figure out the Controller/Action
 	var route *RouteMatch = MainRouter.Route(r)
 	if route == nil {
@@ -144,6 +155,13 @@ func Run(port int) {
 		MainTemplateLoader.Refresh()
 	}
 
+	MainAssets = newAppAssetManager()
+	if MainWatcher != nil && Config.BoolDefault("watch.assets", true) && DirExists(MainAssets.path) {
+		MainWatcher.Listen(MainAssets, MainAssets.path)
+	} else if err := MainAssets.Refresh(); err != nil {
+		ERROR.Fatalln("Failed to load the assets:", err)
+	}
+
 	if MainWatcher != nil && Config.BoolDefault("watch.routes", true) {
 		MainWatcher.auditor = PluginNotifier{plugins}
 		MainWatcher.Listen(MainRouter, MainRouter.path)
@@ -164,9 +182,35 @@ func Run(port int) {
 		fmt.Printf("Listening on port %d...\n", port)
 	}()
 
+	// If asked to, let the plugins stop cleanly on interrupt or terminate.
+	if Config.BoolDefault("app.handle_signals", false) {
+		go handleSignals()
+	}
+
 	ERROR.Fatalln("Failed to listen:", Server.ListenAndServe())
 }
 
+// Stop the plugins (see StoppingPlugin).  Apps that handle signals themselves,
+// rather than setting app.handle_signals, call it before they exit.
+func StopApp() {
+	plugins.OnAppStop()
+}
+
+// Stop the app on interrupt or terminate, and exit like a process killed by
+// the signal would, e.g. with 130 for SIGINT.
+func handleSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	sig := <-ch
+	INFO.Println("Stopping the server:", sig)
+	StopApp()
+	status := 1
+	if sysSig, ok := sig.(syscall.Signal); ok {
+		status = 128 + int(sysSig)
+	}
+	os.Exit(status)
+}
+
 // The PluginNotifier glues the watcher and the plugin collection together.
 // It audits refreshes and invokes the appropriate method to inform the plugins.
 type PluginNotifier struct {
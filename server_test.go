@@ -148,3 +148,23 @@ func startFakeBookingApp(b *testing.B) {
 	resp.Body = nil
 	b.ResetTimer()
 }
+
+type stoppingPlugin struct {
+	EmptyPlugin
+	stopped *bool
+}
+
+func (p stoppingPlugin) OnAppStop() {
+	*p.stopped = true
+}
+
+func TestStopApp(t *testing.T) {
+	defer func(registered PluginCollection) { plugins = registered }(plugins)
+	stopped := false
+	plugins = PluginCollection{EmptyPlugin{}, stoppingPlugin{stopped: &stopped}}
+
+	StopApp()
+	if !stopped {
+		t.Error("Expected the stopping plugin to be stopped")
+	}
+}
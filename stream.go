@@ -0,0 +1,137 @@
+package revel
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+)
+
+// Returned by writes to a StreamWriter after the client has disconnected.
+var ErrClientDisconnected = errors.New("client disconnected")
+
+// A writer of a streamed response.  The response is sent with chunked transfer
+// encoding, a chunk at each Flush.
+type StreamWriter struct {
+	resp   *Response
+	closed <-chan bool
+	// Set once the client is seen to have disconnected, since the close
+	// notification is only sent once.
+	disconnected bool
+	// If true, flush after every write.
+	autoFlush bool
+}
+
+func newStreamWriter(resp *Response) *StreamWriter {
+	w := &StreamWriter{resp: resp}
+	if closeNotifier, ok := resp.Out.(http.CloseNotifier); ok {
+		w.closed = closeNotifier.CloseNotify()
+	}
+	return w
+}
+
+// Write to the response.  Fails with ErrClientDisconnected once the client
+// has gone away, at which point the action should stop streaming.
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	if w.Disconnected() {
+		return 0, ErrClientDisconnected
+	}
+	n, err := w.resp.Out.Write(p)
+	if err == nil && w.autoFlush {
+		w.Flush()
+	}
+	return n, err
+}
+
+// Send everything written so far to the client.
+func (w *StreamWriter) Flush() {
+	if flusher, ok := w.resp.Out.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Return a channel that receives a value when the client disconnects, for
+// actions that wait on other events between writes.
+// (It never receives if the server can not tell)
+func (w *StreamWriter) Closed() <-chan bool {
+	return w.closed
+}
+
+// Return true if the client has disconnected.
+func (w *StreamWriter) Disconnected() bool {
+	if w.disconnected {
+		return true
+	}
+	select {
+	case <-w.closed:
+		w.disconnected = true
+	default:
+	}
+	return w.disconnected
+}
+
+// This result streams the response written by its func, e.g. a large export:
+//
+//   return c.RenderStream("text/csv", func(w *revel.StreamWriter) error {
+//       for rows.Next() {
+//           ...
+//           if _, err := fmt.Fprintf(w, "%s,%d\n", name, count); err != nil {
+//               return err
+//           }
+//           w.Flush()
+//       }
+//       return rows.Err()
+//   })
+//
+// Since the status has been sent by the time the func returns, an error it
+// returns is only logged.
+type StreamResult struct {
+	ContentType string
+	Stream      func(w *StreamWriter) error
+}
+
+func (r *StreamResult) Apply(req *Request, resp *Response) {
+	// Without a Content-Length, the response is sent with chunked encoding.
+	resp.Out.Header().Del("Content-Length")
+	resp.WriteHeader(http.StatusOK, r.ContentType)
+
+	w := newStreamWriter(resp)
+	defer func() {
+		if err := recover(); err != nil {
+			ERROR.Println("Panic while streaming", req.URL.Path, ":", err)
+		}
+	}()
+	if err := r.Stream(w); err != nil {
+		if err == ErrClientDisconnected {
+			TRACE.Println("Client disconnected while streaming", req.URL.Path)
+		} else {
+			ERROR.Println("Error while streaming", req.URL.Path, ":", err)
+		}
+		return
+	}
+	w.Flush()
+}
+
+// The size of the chunks in which streamed templates are flushed.
+const templateStreamChunkSize = 4096
+
+// Render the template into the response, flushing a chunk every few KB.
+func streamTemplate(tmpl Template, renderArgs map[string]interface{}, req *Request, resp *Response) {
+	resp.Out.Header().Del("Content-Length")
+	resp.WriteHeader(http.StatusOK, templateContentType(tmpl))
+
+	w := newStreamWriter(resp)
+	w.autoFlush = true
+	chunks := bufio.NewWriterSize(w, templateStreamChunkSize)
+	err := tmpl.Render(chunks, renderArgs)
+	if err == nil {
+		err = chunks.Flush()
+	}
+	if err != nil {
+		if w.Disconnected() {
+			TRACE.Println("Client disconnected while streaming", tmpl.Name())
+			return
+		}
+		// The status has been sent already, so the error can only be logged.
+		ERROR.Println("Failed to stream template", tmpl.Name(), "\n", err)
+	}
+}
@@ -0,0 +1,135 @@
+package revel
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// A recorder that can report the client disconnecting.
+type closeNotifyRecorder struct {
+	*httptest.ResponseRecorder
+	closed chan bool
+}
+
+func (r *closeNotifyRecorder) CloseNotify() <-chan bool {
+	return r.closed
+}
+
+func newStreamTestRequest() (*Request, *closeNotifyRecorder) {
+	httpReq, _ := http.NewRequest("GET", "/export", nil)
+	return NewRequest(httpReq), &closeNotifyRecorder{httptest.NewRecorder(), make(chan bool, 1)}
+}
+
+func TestStreamResult(t *testing.T) {
+	req, recorder := newStreamTestRequest()
+	var flushedBeforeEnd bool
+	result := &StreamResult{
+		ContentType: "text/csv",
+		Stream: func(w *StreamWriter) error {
+			fmt.Fprintf(w, "a,1\n")
+			w.Flush()
+			flushedBeforeEnd = recorder.Flushed
+			fmt.Fprintf(w, "b,2\n")
+			return nil
+		},
+	}
+	result.Apply(req, NewResponse(recorder))
+
+	if !flushedBeforeEnd {
+		t.Error("Expected the response to be flushed while streaming")
+	}
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", recorder.Code)
+	}
+	if contentType := recorder.HeaderMap.Get("Content-Type"); !strings.HasPrefix(contentType, "text/csv") {
+		t.Errorf("Expected text/csv, got %s", contentType)
+	}
+	if body := recorder.Body.String(); body != "a,1\nb,2\n" {
+		t.Errorf("Unexpected body: %q", body)
+	}
+}
+
+func TestStreamResultDisconnect(t *testing.T) {
+	req, recorder := newStreamTestRequest()
+	var err error
+	result := &StreamResult{
+		ContentType: "text/plain",
+		Stream: func(w *StreamWriter) error {
+			fmt.Fprintf(w, "first")
+			recorder.closed <- true
+			if !w.Disconnected() {
+				t.Error("Expected the writer to see the client disconnect")
+			}
+			_, err = fmt.Fprintf(w, "second")
+			return err
+		},
+	}
+	result.Apply(req, NewResponse(recorder))
+
+	if err != ErrClientDisconnected {
+		t.Errorf("Expected ErrClientDisconnected, got %v", err)
+	}
+	if body := recorder.Body.String(); body != "first" {
+		t.Errorf("Expected only the first write, got %q", body)
+	}
+}
+
+func TestRenderTemplateStream(t *testing.T) {
+	loader := NewTemplateLoader([]string{testViewsPath})
+	if err := loader.Refresh(); err != nil {
+		t.Fatalf("Failed to load templates: %s", err)
+	}
+	tmpl, err := loader.Template("Hotels/Show.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, recorder := newStreamTestRequest()
+	result := &RenderTemplateResult{
+		Template:   tmpl,
+		RenderArgs: map[string]interface{}{"name": "Marriott"},
+		Stream:     true,
+	}
+	result.Apply(req, NewResponse(recorder))
+
+	if !recorder.Flushed {
+		t.Error("Expected the streamed template to be flushed")
+	}
+	expected := renderTestTemplate(t, loader, "Hotels/Show.html", result.RenderArgs)
+	if body := strings.Replace(recorder.Body.String(), "\n", "", -1); body != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+}
+
+func TestRenderTemplateStreamContentType(t *testing.T) {
+	defer func(confPaths []string) { ConfPaths, mimeConfig = confPaths, nil }(ConfPaths)
+	ConfPaths = []string{"conf"}
+	LoadMimeConfig()
+	loader := NewTemplateLoader([]string{testViewsPath})
+	if err := loader.Refresh(); err != nil {
+		t.Fatalf("Failed to load templates: %s", err)
+	}
+
+	for name, expected := range map[string]string{
+		"Hotels/Show.json": "application/json",
+		"Hotels/Show.html": "text/html; charset=utf-8",
+	} {
+		tmpl, err := loader.Template(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, recorder := newStreamTestRequest()
+		result := &RenderTemplateResult{
+			Template:   tmpl,
+			RenderArgs: map[string]interface{}{"name": "Marriott"},
+			Stream:     true,
+		}
+		result.Apply(req, NewResponse(recorder))
+		if contentType := recorder.HeaderMap.Get("Content-Type"); contentType != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, contentType)
+		}
+	}
+}
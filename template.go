@@ -1,12 +1,13 @@
 package revel
 
 import (
+	"bytes"
 	"fmt"
 	"html"
 	"html/template"
 	"io"
-	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"regexp"
@@ -20,8 +21,8 @@ var ERROR_CLASS = "hasError"
 // This object handles loading and parsing of templates.
 // Everything below the application's views directory is treated as a template.
 type TemplateLoader struct {
-	// This is the set of all templates under views
-	templateSet *template.Template
+	// Map from template name to the engine that parsed it.
+	templateEngines map[string]TemplateEngine
 	// If an error was encountered parsing the templates, it is stored here.
 	compileError *Error
 	// Paths to search for templates, in priority order.
@@ -43,6 +44,20 @@ var (
 	// The functions available for use in the templates.
 	TemplateFuncs = map[string]interface{}{
 		"url": ReverseUrl,
+		// The URL of the current page in the given locale, or in each language.
+		// {{localeUrl . "fr"}}
+		"localeUrl": func(renderArgs map[string]interface{}, locale string) string {
+			if req := renderArgsRequest(renderArgs); req != nil {
+				return LocaleUrl(req, locale)
+			}
+			return ""
+		},
+		"localeUrls": func(renderArgs map[string]interface{}) map[string]string {
+			if req := renderArgsRequest(renderArgs); req != nil {
+				return LocaleUrls(req)
+			}
+			return nil
+		},
 		// <div class="message {{if eq .User "you"}}you{{end}}">
 		"eq": func(a, b interface{}) bool { return a == b },
 		// {{set . "title" "Basic Chat room"}}
@@ -61,6 +76,12 @@ var (
 			return template.HTML("")
 		},
 		"field": NewField,
+		"asset": assetUrl,
+		// Declares the layout of a view (see TemplateLoader).  It renders nothing.
+		// {{layout "layouts/main.html"}}
+		"layout": func(name string) template.HTML {
+			return template.HTML("")
+		},
 		//	{{with $field := field "booking.Beds" .}}
 		//	<select name="{{$field.Name}}">
 		//  		{{option $field "1" "One king-size bed"}}
@@ -89,6 +110,17 @@ var (
 				html.EscapeString(f.Name), html.EscapeString(val), checked))
 		},
 
+		// Form helpers, see field.go.
+		//	{{with $field := field "booking.NameOnCard" .}}
+		//	  {{label $field "Name on card"}} {{input $field "text"}} {{fieldErrors $field}}
+		//	{{end}}
+		"input":       formInput,
+		"textarea":    formTextarea,
+		"checkbox":    formCheckbox,
+		"select":      formSelect,
+		"label":       formLabel,
+		"fieldErrors": formFieldErrors,
+
 		// Pads the given string with &nbsp;'s up to the given width.
 		"pad": func(str string, width int) template.HTML {
 			if len(str) >= width {
@@ -110,6 +142,8 @@ var (
 			return template.HTML(ERROR_CLASS)
 		},
 
+		// Named arguments are given with args, e.g.
+		// {{msg . "hotels.count" (args "count" .count "city" .city)}}
 		"msg": func(renderArgs map[string]interface{}, message string, args ...interface{}) template.HTML {
 			return template.HTML(Message(renderArgs[CurrentLocaleRenderArg].(string), message, args...))
 		},
@@ -152,13 +186,52 @@ var (
 			return singular
 		},
 
-		// Format a date according to the application's default date(time) format.
-		"date": func(date time.Time) string {
-			return date.Format(DateFormat)
+		// Format a date according to the current locale's date(time) format
+		// (format.date or format.datetime in its messages), or else the
+		// application's default one.  Like url, they are given the render args
+		// of the page when the template is parsed (see localizeCalls).
+		"date": func(page interface{}, date time.Time) string {
+			renderArgs, _ := page.(map[string]interface{})
+			f := renderArgsFormatter(renderArgs)
+			return f.formatTime(date, f.appDateLayout(false))
+		},
+		"datetime": func(page interface{}, date time.Time) string {
+			renderArgs, _ := page.(map[string]interface{})
+			f := renderArgsFormatter(renderArgs)
+			return f.formatTime(date, f.appDateLayout(true))
+		},
+
+		// Format numbers, money, percentages and dates for the current locale.
+		// (See Formatter)
+		"formatNumber": func(renderArgs map[string]interface{}, value interface{}, decimals ...int) string {
+			return renderArgsFormatter(renderArgs).Number(value, decimals...)
+		},
+		"formatCurrency": func(renderArgs map[string]interface{}, value interface{}, currency string) string {
+			return renderArgsFormatter(renderArgs).Currency(value, currency)
+		},
+		"formatPercent": func(renderArgs map[string]interface{}, value interface{}, decimals ...int) string {
+			return renderArgsFormatter(renderArgs).Percent(value, decimals...)
+		},
+		"formatDate": func(renderArgs map[string]interface{}, date time.Time, style ...string) string {
+			return renderArgsFormatter(renderArgs).Date(date, style...)
 		},
-		"datetime": func(date time.Time) string {
-			return date.Format(DateTimeFormat)
+		"relativeTime": func(renderArgs map[string]interface{}, date time.Time) string {
+			return renderArgsFormatter(renderArgs).RelativeTime(date)
 		},
+
+		// Render a partial template with its own arguments, rather than the
+		// render args of the page.  Given the render args of the page first,
+		// it also gets their locale, controller, errors and flash:
+		// {{render . "Hotels/_card.html" (args "hotel" .hotel "compact" true)}}
+		"render": renderPartial,
+		"args":   partialArgs,
+
+		// Render a partial template like "render", caching the result in the
+		// MainFragmentCache under the key, for a time to live given in seconds or
+		// as a duration, for each locale.  (It is a func, not a block: the cached
+		// part is a partial, which gets only the locale of the page)
+		// {{cache . "hotels.list" "10m" "Hotels/_list.html" (args "hotels" .hotels)}}
+		"cache": cachePartial,
 	}
 	// Applications may register custom functions to use in templates.
 	// Here is an example:
@@ -175,22 +248,48 @@ func NewTemplateLoader(paths []string) *TemplateLoader {
 	return loader
 }
 
-// This scans the views directory and parses all templates as Go Templates.
+// This scans the views directory and parses all templates with the engine
+// for their extension (see TemplateEngine).
 // If a template fails to parse, the error is set on the loader.
 // (It's awkward to refresh a single Go Template)
+//
+// Views may be rendered within a layout.  A view declares its layout with
+// {{layout "layouts/main.html"}}, or else the layout of its controller is used,
+// if there is one (e.g. layouts/Hotels.html for the view Hotels/Show.html).
+// Partials (e.g. Hotels/_card.html) do not get the layout of their controller.
+// {{layout ""}} renders a view without a layout.
+//
+// The layout defines named blocks with their default content, which the view
+// may fill in by defining templates of the same name:
+//
+//	layouts/main.html:
+//	  <html><head><title>{{block "title" .}}My app{{end}}</title></head>
+//	  <body>{{block "content" .}}{{end}}</body></html>
+//
+//	Hotels/Show.html:
+//	  {{layout "layouts/main.html"}}
+//	  {{define "title"}}{{.hotel.Name}}{{end}}
+//	  {{define "content"}}<h1>{{.hotel.Name}}</h1>{{end}}
+//
+// Layouts may in turn declare a layout of their own, filling in its blocks.
 func (loader *TemplateLoader) Refresh() *Error {
 	TRACE.Printf("Refreshing templates from %s", loader.paths)
 
 	loader.compileError = nil
 	loader.templatePaths = map[string]string{}
 
-	// Walk through the template loader's paths and build up a template set.
-	var templateSet *template.Template = nil
+	// Cached fragments may have been rendered by the old templates.
+	MainFragmentCache.Clear()
+
+	// Walk through the template loader's paths and parse each template with
+	// the engine for its extension.
+	engines := map[string]TemplateEngine{}
+	templateEngines := map[string]TemplateEngine{}
 	for _, basePath := range loader.paths {
 
 		// Walk only returns an error if the template loader is completely unusable
 		// (namely, if one of the TemplateFuncs does not have an acceptable signature).
-		funcErr := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
+		funcErr := WalkFiles(basePath, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				ERROR.Println("error walking templates:", err)
 				return nil
@@ -220,15 +319,24 @@ func (loader *TemplateLoader) Refresh() *Error {
 			}
 			loader.templatePaths[templateName] = path
 
-			fileBytes, err := ioutil.ReadFile(path)
+			fileBytes, err := ReadFile(path)
 			if err != nil {
 				ERROR.Println("Failed reading file:", path)
 				return nil
 			}
 
 			fileStr := string(fileBytes)
-			if templateSet == nil {
-				// Create the template set.  This panics if any of the funcs do not
+			engineName := templateEngineName(templateName)
+			engine, ok := engines[engineName]
+			if !ok {
+				newEngine, ok := templateEngineFactories[engineName]
+				if !ok {
+					loader.setCompileError(templateName, fileStr,
+						fmt.Errorf("template: %s:1: unknown template engine %q", templateName, engineName))
+					return nil
+				}
+
+				// Create the engine.  This panics if any of the funcs do not
 				// conform to expectations, so we wrap it in a func and handle those
 				// panics by serving an error page.
 				var funcError *Error
@@ -241,30 +349,21 @@ func (loader *TemplateLoader) Refresh() *Error {
 							}
 						}
 					}()
-					templateSet = template.New(templateName).Funcs(TemplateFuncs)
-					_, err = templateSet.Parse(fileStr)
+					engine = newEngine(loader)
 				}()
 
 				if funcError != nil {
 					return funcError
 				}
-
-			} else {
-				_, err = templateSet.New(templateName).Parse(fileStr)
+				engines[engineName] = engine
 			}
 
+			templateEngines[templateName] = engine
+			err = engine.ParseAndAdd(templateName, fileStr)
+
 			// Store / report the first error encountered.
-			if err != nil && loader.compileError == nil {
-				_, line, description := parseTemplateError(err)
-				loader.compileError = &Error{
-					Title:       "Template Compilation Error",
-					Path:        templateName,
-					Description: description,
-					Line:        line,
-					SourceLines: strings.Split(fileStr, "\n"),
-				}
-				ERROR.Printf("Template compilation error (In %s around line %d):\n%s",
-					templateName, line, description)
+			if err != nil {
+				loader.setCompileError(templateName, fileStr, err)
 			}
 			return nil
 		})
@@ -276,11 +375,135 @@ func (loader *TemplateLoader) Refresh() *Error {
 		}
 	}
 
+	if loader.compileError == nil {
+		for _, engine := range engines {
+			if err := engine.Finish(); err != nil {
+				loader.setCompileError("", "", err)
+				break
+			}
+		}
+	}
+
 	// Note: compileError may or may not be set.
-	loader.templateSet = templateSet
+	loader.templateEngines = templateEngines
 	return loader.compileError
 }
 
+// Store / report the given template compilation error, if it is the first.
+func (loader *TemplateLoader) setCompileError(templateName, fileStr string, err error) {
+	if loader.compileError != nil {
+		return
+	}
+	errTemplateName, line, description := parseTemplateError(err)
+	if errTemplateName != "" && errTemplateName != templateName {
+		// The error is located in another file, e.g. in a layout.
+		if errTemplatePath, ok := loader.templatePaths[errTemplateName]; ok {
+			templateName = errTemplateName
+			fileStr = ""
+			if fileBytes, err := ReadFile(errTemplatePath); err == nil {
+				fileStr = string(fileBytes)
+			}
+		}
+	}
+	loader.compileError = &Error{
+		Title:       "Template Compilation Error",
+		Path:        templateName,
+		Description: description,
+		Line:        line,
+		SourceLines: strings.Split(fileStr, "\n"),
+	}
+	ERROR.Printf("Template compilation error (In %s around line %d):\n%s",
+		templateName, line, description)
+}
+
+var layoutPattern = regexp.MustCompile(`\{\{-?\s*layout\s+"([^"]*)"\s*-?\}\}`)
+
+// The maximum depth of nested layouts, to detect cycles.
+const maxLayoutDepth = 10
+
+// Compose each view that has a layout with its chain of layouts.  The views
+// are grouped by their chain of layouts (from the outermost one), and compose
+// is called once for each distinct chain, with the views that use it, e.g. so
+// that engines may prepare the layouts of a chain once.
+func composeLayouts(sources map[string]string, compose func(layouts []string, views []string) error) error {
+	var (
+		layoutChains = map[string][]string{} // Keyed by the layout names, joined.
+		chainViews   = map[string][]string{}
+	)
+	for name := range sources {
+		if strings.HasPrefix(name, "layouts/") {
+			continue
+		}
+
+		chain, err := layoutChain(name, sources)
+		if err != nil {
+			return err
+		}
+		if len(chain) < 2 {
+			continue
+		}
+
+		layouts := chain[:len(chain)-1]
+		key := strings.Join(layouts, "\x00")
+		layoutChains[key] = layouts
+		chainViews[key] = append(chainViews[key], name)
+	}
+
+	for key, layouts := range layoutChains {
+		if err := compose(layouts, chainViews[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return the names of the templates making up the given view, from the
+// outermost layout to the view itself.
+func layoutChain(name string, sources map[string]string) ([]string, error) {
+	chain := []string{name}
+	layout, declared := declaredLayout(sources[name])
+	if !declared && !strings.HasPrefix(path.Base(name), "_") {
+		// Use the controller's layout, if it has one.  (Partials, whose names
+		// begin with an underscore, are rendered without it)
+		if slash := strings.Index(name, "/"); slash != -1 {
+			controllerLayout := "layouts/" + name[:slash] + path.Ext(name)
+			if _, ok := sources[controllerLayout]; ok {
+				layout = controllerLayout
+			}
+		}
+	}
+
+	for layout != "" {
+		if _, ok := sources[layout]; !ok {
+			return nil, fmt.Errorf("template: %s:%d: layout %q not found",
+				chain[0], declaredLayoutLine(sources[chain[0]]), layout)
+		}
+		if len(chain) > maxLayoutDepth || ContainsString(chain, layout) {
+			return nil, fmt.Errorf("template: %s:%d: layouts nested too deeply (or in a cycle): %s",
+				chain[0], declaredLayoutLine(sources[chain[0]]), layout)
+		}
+		chain = append([]string{layout}, chain...)
+		layout, _ = declaredLayout(sources[layout])
+	}
+	return chain, nil
+}
+
+// Return the layout declared by the given template source, if any.
+func declaredLayout(src string) (layout string, declared bool) {
+	if matches := layoutPattern.FindStringSubmatch(src); matches != nil {
+		return matches[1], true
+	}
+	return "", false
+}
+
+// Return the line on which the layout is declared in the template source.
+func declaredLayoutLine(src string) int {
+	if loc := layoutPattern.FindStringIndex(src); loc != nil {
+		return strings.Count(src[:loc[0]], "\n") + 1
+	}
+	return 0
+}
+
 func (loader *TemplateLoader) WatchDir(info os.FileInfo) bool {
 	// Watch all directories, except the ones starting with a dot.
 	return !strings.HasPrefix(info.Name(), ".")
@@ -317,8 +540,11 @@ func parseTemplateError(err error) (templateName string, line int, description s
 // An Error is returned if there was any problem with any of the templates.  (In
 // this case, if a template is returned, it may still be usable.)
 func (loader *TemplateLoader) Template(name string) (Template, error) {
-	// Look up and return the template.
-	tmpl := loader.templateSet.Lookup(name)
+	// Look up and return the template from the engine that parsed it.
+	var tmpl Template
+	if engine, ok := loader.templateEngines[name]; ok {
+		tmpl = engine.Lookup(name)
+	}
 
 	// This is necessary.
 	// If a nil loader.compileError is returned directly, a caller testing against
@@ -332,32 +558,163 @@ func (loader *TemplateLoader) Template(name string) (Template, error) {
 		return nil, fmt.Errorf("Template %s not found.", name)
 	}
 
-	return GoTemplate{tmpl, loader}, err
+	return tmpl, err
 }
 
-// Adapter for Go Templates.
-type GoTemplate struct {
-	*template.Template
-	loader *TemplateLoader
+/////////////////////
+// Template functions
+/////////////////////
+
+// The render args of the page that its partials get too, unless they are
+// given others: the locale, the controller, the validation errors and flash.
+// Cached fragments are shared by all requests, so they only get the locale.
+var (
+	inheritedRenderArgs      = []string{CurrentLocaleRenderArg, "Controller", "errors", "flash"}
+	cacheInheritedRenderArgs = []string{CurrentLocaleRenderArg}
+)
+
+// Render the named template with the given arguments (see partialArgs), and
+// the inherited render args of the page, if given first:
+// . "Hotels/_card.html" (args "hotel" .hotel)
+func renderPartial(args ...interface{}) (template.HTML, error) {
+	pageArgs, args := splitPageArgs(args)
+	name, renderArgs, err := partialNameAndArgs("render", pageArgs, inheritedRenderArgs, args)
+	if err != nil {
+		return "", err
+	}
+	return renderPartialArgs(name, renderArgs)
 }
 
-// return a 'revel.Template' from Go's template.
-func (gotmpl GoTemplate) Render(wr io.Writer, arg interface{}) error {
-	return gotmpl.Execute(wr, arg)
+func renderPartialArgs(name string, renderArgs map[string]interface{}) (template.HTML, error) {
+	if MainTemplateLoader == nil {
+		return "", fmt.Errorf("render: templates have not been loaded")
+	}
+	tmpl, err := MainTemplateLoader.Template(name)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err = tmpl.Render(&b, renderArgs); err != nil {
+		return "", err
+	}
+	return template.HTML(b.String()), nil
 }
 
-func (gotmpl GoTemplate) Content() []string {
-	content, _ := ReadLines(gotmpl.loader.templatePaths[gotmpl.Name()])
-	return content
+// Split off the render args of the page, if they are given first.
+func splitPageArgs(args []interface{}) (map[string]interface{}, []interface{}) {
+	if len(args) > 0 {
+		if pageArgs, ok := args[0].(map[string]interface{}); ok {
+			return pageArgs, args[1:]
+		}
+	}
+	return nil, args
 }
 
-/////////////////////
-// Template functions
-/////////////////////
+// Return the name of the partial and its render args, from the arguments of
+// the template func (the name, and the partial's args, if any) and the
+// inherited args of the page.
+func partialNameAndArgs(funcName string, pageArgs map[string]interface{}, inherited []string, args []interface{}) (string, map[string]interface{}, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return "", nil, fmt.Errorf("%s: expected a template name and args, got %d arguments", funcName, len(args))
+	}
+	name, ok := args[0].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("%s: expected a template name, got %v", funcName, args[0])
+	}
+
+	renderArgs := map[string]interface{}{}
+	if len(args) == 2 && args[1] != nil {
+		given, ok := args[1].(map[string]interface{})
+		if !ok {
+			return "", nil, fmt.Errorf("%s: expected args, got %v", funcName, args[1])
+		}
+		for key, value := range given {
+			renderArgs[key] = value
+		}
+	}
+	for _, key := range inherited {
+		if _, given := renderArgs[key]; !given {
+			if value, found := pageArgs[key]; found {
+				renderArgs[key] = value
+			}
+		}
+	}
+	return name, renderArgs, nil
+}
+
+// Return a map of the given names and values, for a partial template:
+// (args "hotel" .hotel "compact" true) => {"hotel": .hotel, "compact": true}
+func partialArgs(namesAndValues ...interface{}) (map[string]interface{}, error) {
+	if len(namesAndValues)%2 != 0 {
+		return nil, fmt.Errorf("args: expected pairs of names and values, got %d arguments", len(namesAndValues))
+	}
+	args := make(map[string]interface{}, len(namesAndValues)/2)
+	for i := 0; i < len(namesAndValues); i += 2 {
+		name, ok := namesAndValues[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("args: expected a name, got %v", namesAndValues[i])
+		}
+		args[name] = namesAndValues[i+1]
+	}
+	return args, nil
+}
+
+// Render the named template like renderPartial, unless it is found in the
+// MainFragmentCache under the key.  The time to live may be a number of seconds
+// or a duration, like "10m".  The fragment is cached for each locale, which is
+// also the one of the URLs in it (see localeUrlConfigKey), and it gets no other
+// render args of the page, as it is shown to all users:
+// . "hotels.list" "10m" "Hotels/_list.html" (args "hotels" .hotels)
+// (Go templates have no custom block actions, so the cached part of the page
+// is a partial template, rather than a {{cache}}...{{end}} block)
+func cachePartial(args ...interface{}) (template.HTML, error) {
+	pageArgs, args := splitPageArgs(args)
+	if len(args) < 3 {
+		return "", fmt.Errorf("cache: expected a key, time to live and template name, got %d arguments", len(args))
+	}
+	key, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("cache: expected a key, got %v", args[0])
+	}
+	name, renderArgs, err := partialNameAndArgs("cache", pageArgs, cacheInheritedRenderArgs, args[2:])
+	if err != nil {
+		return "", err
+	}
+
+	locale, _ := renderArgs[CurrentLocaleRenderArg].(string)
+	if cached, found := MainFragmentCache.GetVariant(key, locale); found {
+		return template.HTML(cached), nil
+	}
+
+	var duration time.Duration
+	switch ttl := args[1].(type) {
+	case int:
+		duration = time.Duration(ttl) * time.Second
+	case string:
+		if duration, err = time.ParseDuration(ttl); err != nil {
+			return "", fmt.Errorf("cache: invalid time to live %q: %s", ttl, err)
+		}
+	default:
+		return "", fmt.Errorf("cache: invalid time to live %v", ttl)
+	}
+
+	rendered, err := renderPartialArgs(name, renderArgs)
+	if err != nil {
+		return "", err
+	}
+	MainFragmentCache.SetVariant(key, locale, string(rendered), duration)
+	return rendered, nil
+}
 
 // Return a url capable of invoking a given controller method:
 // "Application.ShowApp 123" => "/app/123"
+// The url begins with the current locale (with locale URLs turned on, see
+// localeUrlConfigKey), as the render args of the page are added to the calls
+// of url in Go templates (see localizeCalls):
+// "Application.ShowApp" 123 => "/fr/app/123"
 func ReverseUrl(args ...interface{}) string {
+	locale, args := splitUrlRenderArgs(args)
 	if len(args) == 0 {
 		ERROR.Println("Warning: no arguments provided to url function")
 		return "#"
@@ -378,5 +735,5 @@ func ReverseUrl(args ...interface{}) string {
 		argsByName[methodType.Args[i].Name] = fmt.Sprint(argValue)
 	}
 
-	return MainRouter.Reverse(args[0].(string), argsByName).Url
+	return localizeUrl(locale, MainRouter.Reverse(args[0].(string), argsByName).Url)
 }
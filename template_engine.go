@@ -0,0 +1,297 @@
+package revel
+
+import (
+	"html/template"
+	"io"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+)
+
+// A TemplateEngine parses templates of one kind and looks them up for rendering.
+//
+// The engine for each template is chosen by its file extension: app.conf may
+// name it with "template.engine.<extension>", e.g.
+//
+//   template.engine.csv = text
+//
+// Otherwise .txt, .xml and .json templates use the "text" engine
+// (text/template, which does not escape values) and all others use the engine
+// named by "template.engine", "html" (html/template) by default.
+//
+// A new engine is created each time the templates are refreshed.
+type TemplateEngine interface {
+	// Parse the template of the given name and add it to the engine.
+	// Errors should look like "template: Hotels/Show.html:12: description",
+	// so that the offending line may be shown.
+	ParseAndAdd(templateName string, templateSource string) error
+
+	// Called once all templates have been added, e.g. to compose layouts.
+	Finish() error
+
+	// Return the template of the given name, or nil if there is none.
+	Lookup(templateName string) Template
+}
+
+// Map from engine name to a func that creates a new engine of that kind.
+var templateEngineFactories = map[string]func(loader *TemplateLoader) TemplateEngine{
+	"html": newHtmlTemplateEngine,
+	"text": newTextTemplateEngine,
+}
+
+// The engines used for templates of the given extensions, by default.
+var defaultTemplateEngines = map[string]string{
+	"txt":  "text",
+	"xml":  "text",
+	"json": "text",
+}
+
+// Register a template engine under the given name, so that it may be used
+// for templates by setting "template.engine.<extension>" in app.conf.
+// The TemplateLoader provides the TemplateFuncs and paths of the templates.
+func RegisterTemplateEngine(name string, newEngine func(loader *TemplateLoader) TemplateEngine) {
+	templateEngineFactories[name] = newEngine
+}
+
+// Return the name of the engine for the given template.
+func templateEngineName(templateName string) string {
+	ext := strings.TrimPrefix(path.Ext(templateName), ".")
+	if Config != nil {
+		if name, found := Config.String("template.engine." + ext); found {
+			return name
+		}
+	}
+	if name, ok := defaultTemplateEngines[ext]; ok {
+		return name
+	}
+	if Config != nil {
+		return Config.StringDefault("template.engine", "html")
+	}
+	return "html"
+}
+
+// An engine for Go templates: html/template, which escapes values according to
+// context, or text/template, for formats other than HTML.
+type goTemplateEngine struct {
+	loader *TemplateLoader
+	// This is the set of all templates of the engine under views
+	templateSet goTemplateSet
+	// Map from view name to the view composed with its layouts.
+	layoutTemplates map[string]executableTemplate
+	sources         map[string]string
+	// Map from template name to the trees parsed from its source, which the
+	// sources parsed after it may redefine in the set, e.g. "content".
+	trees map[string]map[string]*parse.Tree
+}
+
+func newHtmlTemplateEngine(loader *TemplateLoader) TemplateEngine {
+	return newGoTemplateEngine(loader, htmlTemplateSet{template.New("").Funcs(TemplateFuncs)})
+}
+
+func newTextTemplateEngine(loader *TemplateLoader) TemplateEngine {
+	return newGoTemplateEngine(loader, textTemplateSet{texttemplate.New("").Funcs(TemplateFuncs)})
+}
+
+func newGoTemplateEngine(loader *TemplateLoader, templateSet goTemplateSet) *goTemplateEngine {
+	return &goTemplateEngine{
+		loader:          loader,
+		templateSet:     templateSet,
+		layoutTemplates: map[string]executableTemplate{},
+		sources:         map[string]string{},
+		trees:           map[string]map[string]*parse.Tree{},
+	}
+}
+
+func (engine *goTemplateEngine) ParseAndAdd(templateName, templateSource string) error {
+	engine.sources[templateName] = templateSource
+	if err := engine.templateSet.Parse(templateName, templateSource); err != nil {
+		return err
+	}
+	trees := map[string]*parse.Tree{}
+	for _, name := range parsedTemplateNames(templateName, templateSource) {
+		if tree := engine.templateSet.Tree(name); tree != nil {
+			trees[name] = tree
+		}
+	}
+	engine.trees[templateName] = trees
+	return nil
+}
+
+// Compose each view with its layouts, by adding copies of the templates of the
+// layouts and the view to the set, under names beginning with the view's, e.g.
+// "Hotels/Show.html#content".  The view's templates take precedence, and the
+// calls between them are renamed to match.
+func (engine *goTemplateEngine) Finish() error {
+	return composeLayouts(engine.sources, func(layouts []string, views []string) error {
+		for _, view := range views {
+			trees := map[string]*parse.Tree{}
+			for _, name := range append(append([]string{}, layouts...), view) {
+				for defined, tree := range engine.trees[name] {
+					trees[defined] = tree
+				}
+			}
+			names := map[string]string{}
+			for defined := range trees {
+				names[defined] = view + "#" + defined
+			}
+			for defined, tree := range trees {
+				tree = tree.Copy()
+				renameTemplateCalls(tree.Root, names)
+				if err := engine.templateSet.AddParseTree(names[defined], tree); err != nil {
+					return err
+				}
+			}
+			// The outermost layout is the one that gets executed.
+			engine.layoutTemplates[view] = engine.templateSet.Lookup(names[layouts[0]])
+		}
+		return nil
+	})
+}
+
+// Rename the templates called in the parse tree, e.g. {{template "content" .}}
+func renameTemplateCalls(node parse.Node, names map[string]string) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node != nil {
+			for _, child := range node.Nodes {
+				renameTemplateCalls(child, names)
+			}
+		}
+	case *parse.TemplateNode:
+		if name, ok := names[node.Name]; ok {
+			node.Name = name
+		}
+	case *parse.IfNode:
+		renameBranchTemplateCalls(&node.BranchNode, names)
+	case *parse.RangeNode:
+		renameBranchTemplateCalls(&node.BranchNode, names)
+	case *parse.WithNode:
+		renameBranchTemplateCalls(&node.BranchNode, names)
+	}
+}
+
+func renameBranchTemplateCalls(branch *parse.BranchNode, names map[string]string) {
+	renameTemplateCalls(branch.List, names)
+	renameTemplateCalls(branch.ElseList, names)
+}
+
+func (engine *goTemplateEngine) Lookup(templateName string) Template {
+	tmpl, ok := engine.layoutTemplates[templateName]
+	if !ok {
+		tmpl = engine.templateSet.Lookup(templateName)
+	}
+	if tmpl == nil {
+		return nil
+	}
+	return GoTemplate{tmpl, engine.loader, templateName}
+}
+
+// A set of Go templates, which may refer to each other.
+type goTemplateSet interface {
+	// Parse the template and add it to the set.
+	Parse(name, source string) error
+	// Add the parsed template to the set, under the given name.
+	AddParseTree(name string, tree *parse.Tree) error
+	// Return the parse tree of the template of the given name, or nil.
+	Tree(name string) *parse.Tree
+	// Return the template of the given name, or nil.
+	Lookup(name string) executableTemplate
+}
+
+// The methods shared by html/template and text/template templates.
+type executableTemplate interface {
+	Name() string
+	Execute(wr io.Writer, data interface{}) error
+}
+
+type htmlTemplateSet struct {
+	set *template.Template
+}
+
+func (s htmlTemplateSet) Parse(name, source string) error {
+	if _, err := s.set.New(name).Parse(source); err != nil {
+		return err
+	}
+	for _, parsed := range parsedTemplateNames(name, source) {
+		if tmpl := s.set.Lookup(parsed); tmpl != nil && tmpl.Tree != nil {
+			localizeCalls(tmpl.Tree.Root)
+		}
+	}
+	return nil
+}
+
+func (s htmlTemplateSet) AddParseTree(name string, tree *parse.Tree) error {
+	_, err := s.set.AddParseTree(name, tree)
+	return err
+}
+
+func (s htmlTemplateSet) Tree(name string) *parse.Tree {
+	if tmpl := s.set.Lookup(name); tmpl != nil {
+		return tmpl.Tree
+	}
+	return nil
+}
+
+func (s htmlTemplateSet) Lookup(name string) executableTemplate {
+	if tmpl := s.set.Lookup(name); tmpl != nil {
+		return tmpl
+	}
+	return nil
+}
+
+type textTemplateSet struct {
+	set *texttemplate.Template
+}
+
+func (s textTemplateSet) Parse(name, source string) error {
+	if _, err := s.set.New(name).Parse(source); err != nil {
+		return err
+	}
+	for _, parsed := range parsedTemplateNames(name, source) {
+		if tmpl := s.set.Lookup(parsed); tmpl != nil && tmpl.Tree != nil {
+			localizeCalls(tmpl.Tree.Root)
+		}
+	}
+	return nil
+}
+
+func (s textTemplateSet) AddParseTree(name string, tree *parse.Tree) error {
+	_, err := s.set.AddParseTree(name, tree)
+	return err
+}
+
+func (s textTemplateSet) Tree(name string) *parse.Tree {
+	if tmpl := s.set.Lookup(name); tmpl != nil {
+		return tmpl.Tree
+	}
+	return nil
+}
+
+func (s textTemplateSet) Lookup(name string) executableTemplate {
+	if tmpl := s.set.Lookup(name); tmpl != nil {
+		return tmpl
+	}
+	return nil
+}
+
+// Adapter for Go Templates, of html/template or text/template.
+type GoTemplate struct {
+	executableTemplate
+	loader *TemplateLoader
+	name   string // The name of the view, which may be composed with layouts.
+}
+
+func (gotmpl GoTemplate) Name() string {
+	return gotmpl.name
+}
+
+// return a 'revel.Template' from Go's template.
+func (gotmpl GoTemplate) Render(wr io.Writer, arg interface{}) error {
+	return gotmpl.Execute(wr, arg)
+}
+
+func (gotmpl GoTemplate) Content() []string {
+	content, _ := ReadLines(gotmpl.loader.templatePaths[gotmpl.Name()])
+	return content
+}
@@ -0,0 +1,277 @@
+package revel
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testViewsPath = "testdata/views"
+
+func renderTestTemplate(t *testing.T, loader *TemplateLoader, name string, args map[string]interface{}) string {
+	tmpl, err := loader.Template(name)
+	if err != nil {
+		t.Fatalf("Failed to load %s: %s", name, err)
+	}
+	var b bytes.Buffer
+	if err = tmpl.Render(&b, args); err != nil {
+		t.Fatalf("Failed to render %s: %s", name, err)
+	}
+	return strings.Replace(b.String(), "\n", "", -1)
+}
+
+func TestTemplateLayouts(t *testing.T) {
+	loader := NewTemplateLoader([]string{testViewsPath})
+	if err := loader.Refresh(); err != nil {
+		t.Fatalf("Failed to load templates: %s", err)
+	}
+
+	args := map[string]interface{}{"name": "Marriott"}
+	var layoutTests = []struct {
+		name, expected string
+	}{
+		// Nested layouts, with the view filling in blocks of both.
+		{"Hotels/Show.html", `<html><head><title>Marriott</title></head><body><div id="hotels"><h1>Marriott</h1></div></body></html>`},
+		// Blocks that are not filled in keep their default content.
+		{"Hotels/Index.html", `<html><head><title>Revel</title></head><body><div id="hotels"><ul></ul></div></body></html>`},
+		// Opting out of the controller's layout.
+		{"Hotels/Plain.html", `<p>Marriott</p>`},
+		// A layout declared by the view.
+		{"Static/About.html", `<html><head><title>Revel</title></head><body>About</body></html>`},
+	}
+	for _, test := range layoutTests {
+		if actual := renderTestTemplate(t, loader, test.name, args); actual != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestComposeLayoutsOncePerChain(t *testing.T) {
+	sources := map[string]string{
+		"layouts/main.html": `{{block "content" .}}{{end}}`,
+		"Hotels/Index.html": `{{layout "layouts/main.html"}}{{define "content"}}index{{end}}`,
+		"Hotels/Show.html":  `{{layout "layouts/main.html"}}{{define "content"}}show{{end}}`,
+		"Static/About.html": `about`,
+	}
+	var calls int
+	err := composeLayouts(sources, func(layouts []string, views []string) error {
+		calls++
+		if len(layouts) != 1 || layouts[0] != "layouts/main.html" || len(views) != 2 {
+			t.Errorf("Unexpected chain %v for %v", layouts, views)
+		}
+		return nil
+	})
+	if err != nil || calls != 1 {
+		t.Errorf("Expected one chain to be composed, got %d (%v)", calls, err)
+	}
+}
+
+func TestTemplateLayoutErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-views")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "layouts"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "layouts", "main.html"), []byte("{{block \"content\" .}}{{end}}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "Missing.html"), []byte("\n{{layout \"layouts/missing.html\"}}"), 0644)
+
+	loader := NewTemplateLoader([]string{dir})
+	compileError := loader.Refresh()
+	if compileError == nil {
+		t.Fatal("Expected an error for a missing layout")
+	}
+	if compileError.Path != "Missing.html" || compileError.Line != 2 {
+		t.Errorf("Expected the error at Missing.html:2, got %s:%d", compileError.Path, compileError.Line)
+	}
+
+	// Fixing the view clears the error on refresh.
+	ioutil.WriteFile(filepath.Join(dir, "Missing.html"), []byte("{{layout \"layouts/main.html\"}}{{define \"content\"}}ok{{end}}"), 0644)
+	if err := loader.Refresh(); err != nil {
+		t.Fatalf("Unexpected error after fixing the view: %s", err)
+	}
+	if actual := renderTestTemplate(t, loader, "Missing.html", nil); actual != "ok" {
+		t.Errorf("Expected ok, got %s", actual)
+	}
+}
+
+func TestComposedViewErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-views")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "layouts"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "layouts", "main.html"),
+		[]byte("{{block \"body\" .}}<main>{{template \"content\" .}}</main>{{end}}{{define \"content\"}}default{{end}}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "Fails.html"),
+		[]byte("{{layout \"layouts/main.html\"}}\n{{define \"content\"}}\n{{.missing.field}}{{end}}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "Works.html"),
+		[]byte("{{layout \"layouts/main.html\"}}{{define \"content\"}}works{{end}}"), 0644)
+
+	loader := NewTemplateLoader([]string{dir})
+	if err := loader.Refresh(); err != nil {
+		t.Fatalf("Failed to load templates: %s", err)
+	}
+	// The block of the layout calls the view's content.
+	if actual := renderTestTemplate(t, loader, "Works.html", nil); actual != "<main>works</main>" {
+		t.Errorf("Expected the view's content, got %s", actual)
+	}
+
+	tmpl, err := loader.Template("Fails.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tmpl.Render(ioutil.Discard, map[string]interface{}{"missing": 1})
+	if err == nil {
+		t.Fatal("Expected an execution error")
+	}
+	name, line, _ := parseTemplateError(err)
+	if name != "Fails.html" || line != 3 {
+		t.Errorf("Expected the error at Fails.html:3, got %s:%d (%s)", name, line, err)
+	}
+	if tmpl.Name() != "Fails.html" || len(tmpl.Content()) != 3 || tmpl.Content()[2] != "{{.missing.field}}{{end}}" {
+		t.Errorf("Expected the view's name and content, got %s: %v", tmpl.Name(), tmpl.Content())
+	}
+}
+
+func TestTemplateEngines(t *testing.T) {
+	loader := NewTemplateLoader([]string{testViewsPath})
+	if err := loader.Refresh(); err != nil {
+		t.Fatalf("Failed to load templates: %s", err)
+	}
+
+	// JSON views are rendered by text/template, so they are not HTML-escaped.
+	args := map[string]interface{}{"name": "Fawlty's <Towers>"}
+	if actual, expected := renderTestTemplate(t, loader, "Hotels/Show.json", args), `{"name": "Fawlty's <Towers>"}`; actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+	if actual, expected := renderTestTemplate(t, loader, "Hotels/Plain.html", args), `<p>Fawlty&#39;s &lt;Towers&gt;</p>`; actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+}
+
+type upperTemplateEngine struct {
+	templates map[string]string
+}
+
+func (engine *upperTemplateEngine) ParseAndAdd(templateName, templateSource string) error {
+	engine.templates[templateName] = strings.ToUpper(templateSource)
+	return nil
+}
+
+func (engine *upperTemplateEngine) Finish() error {
+	return nil
+}
+
+func (engine *upperTemplateEngine) Lookup(templateName string) Template {
+	if src, ok := engine.templates[templateName]; ok {
+		return upperTemplate{templateName, src}
+	}
+	return nil
+}
+
+type upperTemplate struct {
+	name, src string
+}
+
+func (tmpl upperTemplate) Name() string      { return tmpl.name }
+func (tmpl upperTemplate) Content() []string { return strings.Split(tmpl.src, "\n") }
+func (tmpl upperTemplate) Render(wr io.Writer, arg interface{}) error {
+	_, err := io.WriteString(wr, tmpl.src)
+	return err
+}
+
+func TestRegisterTemplateEngine(t *testing.T) {
+	RegisterTemplateEngine("upper", func(loader *TemplateLoader) TemplateEngine {
+		return &upperTemplateEngine{map[string]string{}}
+	})
+	defer delete(templateEngineFactories, "upper")
+	defaultTemplateEngines["json"] = "upper"
+	defer func() { defaultTemplateEngines["json"] = "text" }()
+
+	loader := NewTemplateLoader([]string{testViewsPath})
+	if err := loader.Refresh(); err != nil {
+		t.Fatalf("Failed to load templates: %s", err)
+	}
+	if actual, expected := renderTestTemplate(t, loader, "Hotels/Show.json", nil), `{"NAME": "{{.NAME}}"}`; actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+}
+
+func TestTemplatePartialsAndCache(t *testing.T) {
+	defer func(loader *TemplateLoader) { MainTemplateLoader = loader }(MainTemplateLoader)
+	MainTemplateLoader = NewTemplateLoader([]string{testViewsPath})
+	if err := MainTemplateLoader.Refresh(); err != nil {
+		t.Fatalf("Failed to load templates: %s", err)
+	}
+
+	args := map[string]interface{}{"hotels": []string{"Hilton", "Marriott"}, "featured": "Ritz"}
+	expected := `<div class="card compact">Hilton</div><div class="card compact">Marriott</div><div class="card">Ritz</div>`
+	if actual := renderTestTemplate(t, MainTemplateLoader, "Hotels/List.html", args); actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+
+	// The cached fragment is used until it is invalidated.
+	args["featured"] = "Savoy"
+	if actual := renderTestTemplate(t, MainTemplateLoader, "Hotels/List.html", args); actual != expected {
+		t.Errorf("Expected the cached fragment, got %s", actual)
+	}
+	MainFragmentCache.Delete("hotels.list")
+	if actual := renderTestTemplate(t, MainTemplateLoader, "Hotels/List.html", args); !strings.HasSuffix(actual, `<div class="card">Savoy</div>`) {
+		t.Errorf("Expected the fragment to be rendered again, got %s", actual)
+	}
+
+	if _, err := partialArgs("hotel"); err == nil {
+		t.Error("Expected an error for an odd number of args")
+	}
+}
+
+func TestPartialsInheritRenderArgs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revel-views")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "_greeting.html"), []byte(`{{.currentLocale}}:{{.name}}{{with .flash}}!{{end}}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "Page.html"),
+		[]byte(`{{render . "_greeting.html" (args "name" .name)}} {{cache . "greeting" 60 "_greeting.html" (args "name" .name)}} {{render "_greeting.html" (args "name" .name)}}`), 0644)
+
+	defer func(loader *TemplateLoader) { MainTemplateLoader = loader }(MainTemplateLoader)
+	MainTemplateLoader = NewTemplateLoader([]string{dir})
+	if err := MainTemplateLoader.Refresh(); err != nil {
+		t.Fatalf("Failed to load templates: %s", err)
+	}
+	defer MainFragmentCache.Delete("greeting")
+
+	// The cached fragment gets the locale, but not the flash of the request.
+	args := map[string]interface{}{CurrentLocaleRenderArg: "en", "name": "Rob", "flash": map[string]string{"success": "Saved"}}
+	if actual := renderTestTemplate(t, MainTemplateLoader, "Page.html", args); actual != "en:Rob! en:Rob :Rob" {
+		t.Errorf("Expected the partials to get the locale and flash, got %s", actual)
+	}
+
+	// The fragment is cached for each locale.
+	args[CurrentLocaleRenderArg], args["name"] = "nl", "Bob"
+	if actual := renderTestTemplate(t, MainTemplateLoader, "Page.html", args); actual != "nl:Bob! nl:Bob :Bob" {
+		t.Errorf("Expected the fragment to be rendered for nl, got %s", actual)
+	}
+	args[CurrentLocaleRenderArg] = "en"
+	if actual := renderTestTemplate(t, MainTemplateLoader, "Page.html", args); actual != "en:Bob! en:Rob :Bob" {
+		t.Errorf("Expected the cached fragment for en, got %s", actual)
+	}
+
+	// Deleting the key deletes the fragments of all locales.
+	MainFragmentCache.Delete("greeting")
+	for _, locale := range []string{"en", "nl"} {
+		args[CurrentLocaleRenderArg] = locale
+		if actual := renderTestTemplate(t, MainTemplateLoader, "Page.html", args); actual != locale+":Bob! "+locale+":Bob :Bob" {
+			t.Errorf("Expected the fragment to be rendered again for %s, got %s", locale, actual)
+		}
+	}
+}
@@ -2,6 +2,27 @@ greeting=Hallo
 greeting.name=Rob
 greeting.suffix=, welkom bij Revel!
 
+validation.required=Verplicht
+validation.minsize=De minimale lengte is %d
+user.name.required=Uw naam is verplicht
+
+hotels.count.one={count} hotel
+hotels.count.other={count} hotels
+hotels.booked={hotel}: {count} nachten geboekt door {name}
+
+format.number.decimal=,
+format.number.group=.
+format.currency=¤ #
+format.date.long=2 January 2006
+format.date=2 Jan 2006
+date.July=juli
+date.Jul=jul
+date.May=mei
+date.Friday=vrijdag
+time.ago=%s geleden
+time.minutes.one=%d minuut
+time.minutes.other=%d minuten
+
 [NL]
 greeting=Goeiedag
 
@@ -3,9 +3,7 @@ package revel
 import (
 	"bytes"
 	"io"
-	"io/ioutil"
 	"net/url"
-	"os"
 	"reflect"
 	"regexp"
 	"strings"
@@ -34,7 +32,7 @@ func MustReadLines(filename string) []string {
 
 // Reads the lines of the given file.  Panics in the case of error.
 func ReadLines(filename string) ([]string, error) {
-	bytes, err := ioutil.ReadFile(filename)
+	bytes, err := ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -118,7 +116,7 @@ func ContentTypeByFilename(filename string) string {
 
 // DirExists returns true if the given path exists and is a directory.
 func DirExists(filename string) bool {
-	fileInfo, err := os.Stat(filename)
+	fileInfo, err := StatFile(filename)
 	return err == nil && fileInfo.IsDir()
 }
 
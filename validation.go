@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"reflect"
 	"regexp"
 	"runtime"
+	"time"
 )
 
 //  Exsample of useing the Validation.
@@ -18,7 +20,9 @@ import (
 // 	}
 
 type ValidationError struct {
-	Message, Key string
+	Message string `json:"message"`
+	Key     string `json:"key"`
+	Rule    string `json:"rule"` // The name of the failed validator, e.g. "Required"
 }
 
 // Returns the Message.
@@ -32,7 +36,29 @@ func (e *ValidationError) String() string {
 // A Validation context manages data validation and error messages.
 type Validation struct {
 	Errors []*ValidationError
-	keep   bool
+
+	// In API mode, validation errors are meant to be reported to the client as a
+	// 422 JSON response (see Controller.RenderValidationErrors), instead of being
+	// kept in a cookie for the redirect-after-post flow.
+	// It is turned on for JSON requests and for ValidationApiControllers, but
+	// actions and interceptors may also set it directly.
+	ApiMode bool
+
+	keep    bool
+	request *Request // Used to determine the locale of the validation messages.
+	groups  []string // The active validation groups.
+
+	// If set, validators are recorded in rules instead of being checked.
+	// (This is used to find the rules declared for a form Field)
+	recording bool
+	rules     []*validationRule
+}
+
+// A validator recorded along with the error it would produce.
+// (The error holds the key, which may still be changed by ValidationResult.Key)
+type validationRule struct {
+	Validator Validator
+	Error     *ValidationError
 }
 
 // Tell Revel to serialize the ValidationErrors to the Flash cookie.
@@ -45,6 +71,36 @@ func (v *Validation) Clear() {
 	v.Errors = []*ValidationError{}
 }
 
+// Select the named validation groups to apply, e.g. "create" or "update".
+// This allows a single Validate method to hold different rule sets, chosen by
+// the action:
+//
+//	func (h *Hotel) Validate(v *revel.Validation) {
+//		v.Required(h.Name)
+//		if v.InGroup("create") {
+//			v.Required(h.Owner)
+//		}
+//	}
+//
+//	func (c Hotels) Create(hotel *models.Hotel) revel.Result {
+//		c.Validation.UseGroups("create")
+//		hotel.Validate(c.Validation)
+//		...
+//	}
+func (v *Validation) UseGroups(groups ...string) {
+	v.groups = groups
+}
+
+// Returns true if any of the given validation groups has been selected.
+func (v *Validation) InGroup(groups ...string) bool {
+	for _, group := range groups {
+		if ContainsString(v.groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
 // 如果 validation context 非空的话就返回 true
 // 以此来判断是否有 validation error 发生
 func (v *Validation) HasErrors() bool {
@@ -67,17 +123,28 @@ func (v *Validation) ErrorMap() map[string]*ValidationError {
 // Add an error to the validation context.
 func (v *Validation) Error(message string, args ...interface{}) *ValidationResult {
 	return (&ValidationResult{
-		Ok:    false,
-		Error: &ValidationError{},
-	}).Message(message, args)
+		Ok:     false,
+		Error:  &ValidationError{},
+		locale: v.locale(),
+	}).Message(message, args...)
+}
+
+// Return the locale in which validation messages are looked up.
+// It is empty (and thus the default language) outside of a request.
+func (v *Validation) locale() string {
+	if v.request == nil {
+		return ""
+	}
+	return v.request.Locale
 }
 
 // A ValidationResult is returned from every validation method.
 // It provides an indication of success, and a pointer to the Error (if any).
 // Each evaluation returns a ValidationResult. Failed ValidationResults are stored in the Validation context.
 type ValidationResult struct {
-	Error *ValidationError
-	Ok    bool
+	Error  *ValidationError
+	Ok     bool
+	locale string
 }
 
 func (r *ValidationResult) Key(key string) *ValidationResult {
@@ -92,7 +159,20 @@ func (r *ValidationResult) Message(message string, args ...interface{}) *Validat
 		if len(args) == 0 {
 			r.Error.Message = message
 		} else {
-			r.Error.Message = fmt.Sprintf(message, args)
+			r.Error.Message = fmt.Sprintf(message, args...)
+		}
+	}
+	return r
+}
+
+// Set the error message by looking up the given key in the message files for
+// the current locale, formatting it with the given arguments.
+// If the key can not be found, the existing (default) message is kept.
+//   c.Validation.Required(name).MessageKey("user.name.required")
+func (r *ValidationResult) MessageKey(key string, args ...interface{}) *ValidationResult {
+	if r.Error != nil {
+		if message, found := localizedMessage(r.locale, key, args...); found {
+			r.Error.Message = message
 		}
 	}
 	return r
@@ -103,16 +183,63 @@ func (v *Validation) Required(obj interface{}) *ValidationResult {
 	return v.apply(Required{}, obj)
 }
 
-func (v *Validation) Min(n int, min int) *ValidationResult {
-	return v.apply(Min{min}, n)
+// Test that the argument is non-nil and non-empty, but only if the condition holds.
+//   c.Validation.RequiredIf(hotel.Zip, hotel.Country == "US")
+func (v *Validation) RequiredIf(obj interface{}, condition bool) *ValidationResult {
+	return v.apply(RequiredIf{condition}, obj)
+}
+
+// Test that the argument is non-nil and non-empty, unless the condition holds.
+func (v *Validation) RequiredUnless(obj interface{}, condition bool) *ValidationResult {
+	return v.apply(RequiredUnless{condition}, obj)
 }
 
-func (v *Validation) Max(n int, max int) *ValidationResult {
-	return v.apply(Max{max}, n)
+// Test that the argument is a number (of any int, uint or float kind) no smaller than min.
+// The bound may also be of any number kind, e.g. an int64 variable.
+func (v *Validation) Min(n interface{}, min interface{}) *ValidationResult {
+	if bound, ok := intBound(min); ok {
+		return v.apply(Min{bound}, n)
+	}
+	return v.apply(MinFloat{floatBound(min)}, n)
 }
 
-func (v *Validation) Range(n, min, max int) *ValidationResult {
-	return v.apply(Range{Min{min}, Max{max}}, n)
+// Test that the argument is a number (of any int, uint or float kind) no larger than max.
+func (v *Validation) Max(n interface{}, max interface{}) *ValidationResult {
+	if bound, ok := intBound(max); ok {
+		return v.apply(Max{bound}, n)
+	}
+	return v.apply(MaxFloat{floatBound(max)}, n)
+}
+
+// Test that the argument is a number (of any int, uint or float kind) within min, max inclusive.
+func (v *Validation) Range(n interface{}, min, max interface{}) *ValidationResult {
+	minBound, minOk := intBound(min)
+	maxBound, maxOk := intBound(max)
+	if minOk && maxOk {
+		return v.apply(Range{Min{minBound}, Max{maxBound}}, n)
+	}
+	return v.apply(RangeFloat{MinFloat{floatBound(min)}, MaxFloat{floatBound(max)}}, n)
+}
+
+// Return the bound as an int, if it is of an int or uint kind and fits.
+func intBound(bound interface{}) (int, bool) {
+	n, _ := toNumber(bound)
+	switch n.kind {
+	case reflect.Int64:
+		return int(n.i), int64(int(n.i)) == n.i
+	case reflect.Uint64:
+		return int(n.u), int(n.u) >= 0 && uint64(int(n.u)) == n.u
+	}
+	return 0, false
+}
+
+// Return the bound as a float64.  Panics if it is not a number.
+func floatBound(bound interface{}) float64 {
+	f, ok := toFloat(bound)
+	if !ok {
+		panic(fmt.Sprintf("revel: validation bound %v is not a number", bound))
+	}
+	return f
 }
 
 func (v *Validation) MinSize(obj interface{}, min int) *ValidationResult {
@@ -135,6 +262,70 @@ func (v *Validation) Email(str string) *ValidationResult {
 	return v.apply(Email{Match{emailPattern}}, str)
 }
 
+func (v *Validation) URL(str string) *ValidationResult {
+	return v.apply(URL{}, str)
+}
+
+func (v *Validation) IPAddr(str string) *ValidationResult {
+	return v.apply(IPAddr{}, str)
+}
+
+func (v *Validation) MinDate(t time.Time, min time.Time) *ValidationResult {
+	return v.apply(MinDate{min}, t)
+}
+
+func (v *Validation) MaxDate(t time.Time, max time.Time) *ValidationResult {
+	return v.apply(MaxDate{max}, t)
+}
+
+func (v *Validation) DateRange(t, min, max time.Time) *ValidationResult {
+	return v.apply(DateRange{MinDate{min}, MaxDate{max}}, t)
+}
+
+// Test that the argument is equal to one of the given values.
+//   c.Validation.In(beds, 1, 2, 3)
+func (v *Validation) In(obj interface{}, values ...interface{}) *ValidationResult {
+	return v.apply(In{values}, obj)
+}
+
+// Test that the argument is not equal to any of the given values.
+func (v *Validation) NotIn(obj interface{}, values ...interface{}) *ValidationResult {
+	return v.apply(NotIn{values}, obj)
+}
+
+func (v *Validation) Alpha(str string) *ValidationResult {
+	return v.apply(Alpha{}, str)
+}
+
+func (v *Validation) Alphanumeric(str string) *ValidationResult {
+	return v.apply(Alphanumeric{}, str)
+}
+
+func (v *Validation) UUID(str string) *ValidationResult {
+	return v.apply(UUID{}, str)
+}
+
+// The following compare the argument with a sibling field.
+// The error is keyed on the first argument, as usual.
+//   c.Validation.EqualTo(user.PasswordConfirm, user.Password)
+//   c.Validation.GreaterThan(booking.CheckOutDate, booking.CheckInDate)
+
+func (v *Validation) EqualTo(obj, other interface{}) *ValidationResult {
+	return v.apply(EqualTo{other}, obj)
+}
+
+func (v *Validation) NotEqualTo(obj, other interface{}) *ValidationResult {
+	return v.apply(NotEqualTo{other}, obj)
+}
+
+func (v *Validation) GreaterThan(obj, other interface{}) *ValidationResult {
+	return v.apply(GreaterThan{other}, obj)
+}
+
+func (v *Validation) LessThan(obj, other interface{}) *ValidationResult {
+	return v.apply(LessThan{other}, obj)
+}
+
 // As part of building the app, Revel records the name of the variable being validated, 
 // and uses that as the default key in the validation context (to be looked up later).
 // 我们检测到的错误是使用变量的名字作为key, 保存在 validation context中的，这样我们在后面就很容易的分辨出是那个值的什么类型的无效
@@ -160,7 +351,7 @@ func (v *Validation) Email(str string) *ValidationResult {
 // 	</p>
 
 func (v *Validation) apply(chk Validator, obj interface{}) *ValidationResult {
-	if chk.IsSatisfied(obj) {
+	if !v.recording && chk.IsSatisfied(obj) {
 		return &ValidationResult{Ok: true}
 	}
 
@@ -175,18 +366,66 @@ func (v *Validation) apply(chk Validator, obj interface{}) *ValidationResult {
 		INFO.Println("Failed to get Caller information to look up Validation key")
 	}
 
+	// Only record the rule, if that is what was asked for.
+	if v.recording {
+		rule := &validationRule{chk, &ValidationError{Key: key, Rule: validatorName(chk)}}
+		v.rules = append(v.rules, rule)
+		return &ValidationResult{Ok: true, Error: rule.Error}
+	}
+
 	// Add the error to the validation context.
 	err := &ValidationError{
-		Message: chk.DefaultMessage(),
+		Message: v.message(chk),
 		Key:     key,
+		Rule:    validatorName(chk),
 	}
 	v.Errors = append(v.Errors, err)
 
 	// Also return it in the result.
 	return &ValidationResult{
-		Ok:    false,
-		Error: err,
+		Ok:     false,
+		Error:  err,
+		locale: v.locale(),
+	}
+}
+
+// Return the message for a failed validator.
+// Validators implementing LocalizedValidator have their message looked up in the
+// message files for the current locale.  Otherwise, or if the key is not
+// found, the English DefaultMessage is used.
+func (v *Validation) message(chk Validator) string {
+	if lv, ok := chk.(LocalizedValidator); ok {
+		key, args := lv.MessageKey()
+		if message, found := localizedMessage(v.locale(), key, args...); found {
+			return message
+		}
 	}
+	return chk.DefaultMessage()
+}
+
+// Return the name of the validator type, e.g. "MinSize".
+func validatorName(chk Validator) string {
+	typ := reflect.TypeOf(chk)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Name()
+}
+
+// Look up and format the given message key for the locale.
+// Returns false if no messages are loaded or the key is unknown.
+func localizedMessage(locale, key string, args ...interface{}) (string, bool) {
+	if len(messages) == 0 {
+		return "", false
+	}
+	value, found := messageValue(locale, key)
+	if !found {
+		return "", false
+	}
+	if len(args) > 0 {
+		value = fmt.Sprintf(value, args...)
+	}
+	return value, true
 }
 
 // Apply a group of validators to a field, in order, and return the
@@ -194,21 +433,51 @@ func (v *Validation) apply(chk Validator, obj interface{}) *ValidationResult {
 // succeeds.
 func (v *Validation) Check(obj interface{}, checks ...Validator) *ValidationResult {
 	var result *ValidationResult
+	recorded := len(v.rules)
 	for _, check := range checks {
 		result = v.apply(check, obj)
 		if !result.Ok {
 			return result
 		}
 	}
+
+	// When recording, the rules share a single error so they are keyed together.
+	if v.recording && result != nil {
+		for _, rule := range v.rules[recorded:] {
+			rule.Error = result.Error
+		}
+	}
 	return result
 }
 
+// App controllers may implement ValidationApiController to have their
+// validation errors reported in API mode, regardless of the request format.
+//
+//	func (c Api) ValidationApiMode() bool { return true }
+type ValidationApiController interface {
+	ValidationApiMode() bool
+}
+
+// Types that declare their own validation rules, e.g. the app's models.
+//
+//	func (user User) Validate(v *revel.Validation) {
+//		v.Required(user.Name)
+//	}
+type Validatable interface {
+	Validate(v *Validation)
+}
+
 type ValidationPlugin struct{ EmptyPlugin }
 
 func (p ValidationPlugin) BeforeRequest(c *Controller) {
 	c.Validation = &Validation{
-		Errors: restoreValidationErrors(c.Request.Request),
-		keep:   false,
+		ApiMode: isValidationApiMode(c),
+		keep:    false,
+		request: c.Request,
+	}
+	// API clients get the errors of their own request only.
+	if !c.Validation.ApiMode {
+		c.Validation.Errors = restoreValidationErrors(c.Request.Request)
 	}
 }
 
@@ -216,9 +485,10 @@ func (p ValidationPlugin) AfterRequest(c *Controller) {
 	// Add Validation errors to RenderArgs.
 	c.RenderArgs["errors"] = c.Validation.ErrorMap()
 
-	// Store the Validation errors
+	// Store the Validation errors.  API clients get their errors in the
+	// response, not in the cookie, which is just cleared.
 	var errorsValue string
-	if c.Validation.keep {
+	if c.Validation.keep && !c.Validation.ApiMode {
 		for _, error := range c.Validation.Errors {
 			if error.Message != "" {
 				errorsValue += "\x00" + error.Key + ":" + error.Message + "\x00"
@@ -232,6 +502,49 @@ func (p ValidationPlugin) AfterRequest(c *Controller) {
 	})
 }
 
+// Determine whether validation errors should be reported in API mode:
+// for JSON requests, or if the app controller asks for it.
+func isValidationApiMode(c *Controller) bool {
+	if apiController, ok := c.AppController.(ValidationApiController); ok {
+		return apiController.ValidationApiMode()
+	}
+	return c.Request.Format == "json" || c.Request.ContentType == "application/json"
+}
+
+// In API mode, run the declared validation rules of each bound action argument
+// (those implementing Validatable) before the action is invoked.
+// Returns a 422 result if any of them fail, or nil.
+//
+// This is turned on with "validation.api.auto = true" in app.conf.
+func validateBoundArgs(c *Controller, args []reflect.Value) Result {
+	if c.Validation == nil || !c.Validation.ApiMode ||
+		!Config.BoolDefault("validation.api.auto", false) {
+		return nil
+	}
+
+	for _, arg := range args {
+		if !arg.IsValid() || (arg.Kind() == reflect.Ptr && arg.IsNil()) {
+			continue
+		}
+
+		validatable, ok := arg.Interface().(Validatable)
+		if !ok {
+			// Validate may have been declared on the pointer receiver.
+			ptr := reflect.New(arg.Type())
+			ptr.Elem().Set(arg)
+			if validatable, ok = ptr.Interface().(Validatable); !ok {
+				continue
+			}
+		}
+		validatable.Validate(c.Validation)
+	}
+
+	if c.Validation.HasErrors() {
+		return c.RenderValidationErrors()
+	}
+	return nil
+}
+
 // Restore Validation.Errors from a request.
 func restoreValidationErrors(req *http.Request) []*ValidationError {
 	errors := make([]*ValidationError, 0, 5)
@@ -0,0 +1,103 @@
+package revel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type apiUser struct {
+	Name string
+}
+
+func (u apiUser) Validate(v *Validation) {
+	v.Required(u.Name).Key("user.Name")
+}
+
+type apiController struct {
+	*Controller
+}
+
+func (c apiController) ValidationApiMode() bool { return true }
+
+func TestValidationApiMode(t *testing.T) {
+	httpRequest, _ := http.NewRequest("GET", "/", nil)
+	c := NewController(NewRequest(httpRequest), nil, &ControllerType{reflect.TypeOf(Controller{}), nil})
+	if isValidationApiMode(c) {
+		t.Error("Expected HTML requests not to use API mode")
+	}
+
+	httpRequest.Header.Set("Accept", "application/json")
+	c = NewController(NewRequest(httpRequest), nil, &ControllerType{reflect.TypeOf(Controller{}), nil})
+	if !isValidationApiMode(c) {
+		t.Error("Expected JSON requests to use API mode")
+	}
+
+	httpRequest.Header.Del("Accept")
+	c = NewController(NewRequest(httpRequest), nil, &ControllerType{reflect.TypeOf(Controller{}), nil})
+	c.AppController = &apiController{c}
+	if !isValidationApiMode(c) {
+		t.Error("Expected a ValidationApiController to use API mode")
+	}
+}
+
+func TestValidateBoundArgs(t *testing.T) {
+	loadTestI18nConfig(t)
+	Config.SetOption("validation.api.auto", "true")
+	defer Config.config.RemoveOption(Config.section, "validation.api.auto")
+
+	httpRequest, _ := http.NewRequest("POST", "/users", nil)
+	httpRequest.Header.Set("Accept", "application/json")
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(httpRequest), NewResponse(resp), &ControllerType{reflect.TypeOf(Controller{}), nil})
+	c.Validation = &Validation{ApiMode: true}
+
+	result := validateBoundArgs(c, []reflect.Value{reflect.ValueOf(5), reflect.ValueOf(apiUser{})})
+	if result == nil {
+		t.Fatal("Expected a result for an invalid argument")
+	}
+	result.Apply(c.Request, c.Response)
+
+	if resp.Code != StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", resp.Code)
+	}
+	var body struct {
+		Errors []ValidationError
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal("Failed to decode the response body:", err)
+	}
+	if len(body.Errors) != 1 {
+		t.Fatalf("Expected one validation error, got %v", body.Errors)
+	}
+	if e := body.Errors[0]; e.Key != "user.Name" || e.Rule != "Required" || e.Message == "" {
+		t.Errorf("Unexpected validation error: %#v", e)
+	}
+
+	c.Validation = &Validation{ApiMode: true}
+	if result := validateBoundArgs(c, []reflect.Value{reflect.ValueOf(apiUser{"rob"})}); result != nil {
+		t.Error("Expected no result for a valid argument")
+	}
+}
+
+func TestValidationApiModeIgnoresErrorsCookie(t *testing.T) {
+	httpRequest, _ := http.NewRequest("GET", "/users", nil)
+	httpRequest.Header.Set("Accept", "application/json")
+	httpRequest.AddCookie(&http.Cookie{Name: CookiePrefix + "_ERRORS", Value: "%00user.Name%3ARequired%00"})
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(httpRequest), NewResponse(resp), &ControllerType{reflect.TypeOf(Controller{}), nil})
+
+	ValidationPlugin{}.BeforeRequest(c)
+	if c.Validation.HasErrors() {
+		t.Errorf("Expected no errors from the cookie in API mode, got %v", c.Validation.Errors)
+	}
+
+	c.Validation.Keep()
+	ValidationPlugin{}.AfterRequest(c)
+	if cookie := resp.Header().Get("Set-Cookie"); !strings.HasPrefix(cookie, CookiePrefix+"_ERRORS=;") {
+		t.Errorf("Expected the errors cookie to be cleared, got %q", cookie)
+	}
+}
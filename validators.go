@@ -2,8 +2,13 @@ package revel
 
 import (
 	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"reflect"
 	"regexp"
 	"time"
+	"unicode"
 )
 
 type Validator interface {
@@ -11,6 +16,30 @@ type Validator interface {
 	DefaultMessage() string
 }
 
+// A Validator may also implement LocalizedValidator to have its message looked
+// up in the message files, using the locale of the current request.  The
+// English DefaultMessage is used when the key is not defined.
+//
+// The built-in validators use these keys, formatted with the given arguments:
+//   validation.required
+//   validation.min (min), validation.max (max), validation.range (min, max)
+//     (ints, also for whole float bounds, for %d; use %v for fractional bounds)
+//   validation.minsize (min), validation.maxsize (max), validation.length (n)
+//   validation.match (regexp), validation.email, validation.url, validation.ipaddr
+//   validation.mindate (min), validation.maxdate (max), validation.daterange (min, max)
+//   validation.in (values), validation.notin (values)
+//   validation.alpha, validation.alphanumeric, validation.uuid
+//   validation.equalto, validation.notequalto
+//   validation.greaterthan (other), validation.lessthan (other)
+//
+// For example, in messages/validation.fr:
+//   validation.required=Obligatoire
+//   validation.minsize=La taille minimale est %d
+type LocalizedValidator interface {
+	Validator
+	MessageKey() (key string, args []interface{})
+}
+
 type Required struct{}
 
 func (r Required) IsSatisfied(obj interface{}) bool {
@@ -40,39 +69,83 @@ func (r Required) DefaultMessage() string {
 	return "Required"
 }
 
+func (r Required) MessageKey() (string, []interface{}) {
+	return "validation.required", nil
+}
+
+// Requires the value to be non-empty (see Required), but only if Condition is true.
+// e.g. the zip code is only required when the country is "US".
+type RequiredIf struct {
+	Condition bool
+}
+
+func (r RequiredIf) IsSatisfied(obj interface{}) bool {
+	return !r.Condition || Required{}.IsSatisfied(obj)
+}
+
+func (r RequiredIf) DefaultMessage() string {
+	return Required{}.DefaultMessage()
+}
+
+func (r RequiredIf) MessageKey() (string, []interface{}) {
+	return Required{}.MessageKey()
+}
+
+// Requires the value to be non-empty (see Required), unless Condition is true.
+type RequiredUnless struct {
+	Condition bool
+}
+
+func (r RequiredUnless) IsSatisfied(obj interface{}) bool {
+	return r.Condition || Required{}.IsSatisfied(obj)
+}
+
+func (r RequiredUnless) DefaultMessage() string {
+	return Required{}.DefaultMessage()
+}
+
+func (r RequiredUnless) MessageKey() (string, []interface{}) {
+	return Required{}.MessageKey()
+}
+
+// Requires a number to be greater than or equal to Min.
+// Any int, uint or float kind may be validated.
 type Min struct {
 	Min int
 }
 
 func (m Min) IsSatisfied(obj interface{}) bool {
-	num, ok := obj.(int)
-	if ok {
-		return num >= m.Min
-	}
-	return false
+	return compareBound(obj, intNumber(m.Min)) >= 0
 }
 
 func (m Min) DefaultMessage() string {
 	return fmt.Sprintln("Minimum is", m.Min)
 }
 
+func (m Min) MessageKey() (string, []interface{}) {
+	return "validation.min", []interface{}{m.Min}
+}
+
+// Requires a number to be less than or equal to Max.
+// Any int, uint or float kind may be validated.
 type Max struct {
 	Max int
 }
 
 func (m Max) IsSatisfied(obj interface{}) bool {
-	num, ok := obj.(int)
-	if ok {
-		return num <= m.Max
-	}
-	return false
+	cmp := compareBound(obj, intNumber(m.Max))
+	return cmp <= 0 && cmp != noBound
 }
 
 func (m Max) DefaultMessage() string {
 	return fmt.Sprintln("Maximum is", m.Max)
 }
 
-// Requires an integer to be within Min, Max inclusive.
+func (m Max) MessageKey() (string, []interface{}) {
+	return "validation.max", []interface{}{m.Max}
+}
+
+// Requires a number to be within Min, Max inclusive.
 type Range struct {
 	Min
 	Max
@@ -86,6 +159,197 @@ func (r Range) DefaultMessage() string {
 	return fmt.Sprintln("Range is", r.Min.Min, "to", r.Max.Max)
 }
 
+func (r Range) MessageKey() (string, []interface{}) {
+	return "validation.range", []interface{}{r.Min.Min, r.Max.Max}
+}
+
+// Requires a number to be greater than or equal to the float Min.
+// Any int, uint or float kind may be validated.
+type MinFloat struct {
+	Min float64
+}
+
+func (m MinFloat) IsSatisfied(obj interface{}) bool {
+	return compareBound(obj, number{kind: reflect.Float64, f: m.Min}) >= 0
+}
+
+func (m MinFloat) DefaultMessage() string {
+	return fmt.Sprintln("Minimum is", m.Min)
+}
+
+func (m MinFloat) MessageKey() (string, []interface{}) {
+	return "validation.min", []interface{}{boundArg(m.Min)}
+}
+
+// Requires a number to be less than or equal to the float Max.
+// Any int, uint or float kind may be validated.
+type MaxFloat struct {
+	Max float64
+}
+
+func (m MaxFloat) IsSatisfied(obj interface{}) bool {
+	cmp := compareBound(obj, number{kind: reflect.Float64, f: m.Max})
+	return cmp <= 0 && cmp != noBound
+}
+
+func (m MaxFloat) DefaultMessage() string {
+	return fmt.Sprintln("Maximum is", m.Max)
+}
+
+func (m MaxFloat) MessageKey() (string, []interface{}) {
+	return "validation.max", []interface{}{boundArg(m.Max)}
+}
+
+// Requires a number to be within the float Min, Max inclusive.
+type RangeFloat struct {
+	MinFloat
+	MaxFloat
+}
+
+func (r RangeFloat) IsSatisfied(obj interface{}) bool {
+	return r.MinFloat.IsSatisfied(obj) && r.MaxFloat.IsSatisfied(obj)
+}
+
+func (r RangeFloat) DefaultMessage() string {
+	return fmt.Sprintln("Range is", r.MinFloat.Min, "to", r.MaxFloat.Max)
+}
+
+func (r RangeFloat) MessageKey() (string, []interface{}) {
+	return "validation.range", []interface{}{boundArg(r.MinFloat.Min), boundArg(r.MaxFloat.Max)}
+}
+
+// Return a whole float bound as an int, so that messages may format it with %d.
+// (Messages for fractional bounds should use %v)
+func boundArg(bound float64) interface{} {
+	if bound == math.Trunc(bound) && math.Abs(bound) <= math.MaxInt32 {
+		return int(bound)
+	}
+	return bound
+}
+
+// A number of any int, uint or float kind, kept without loss of precision.
+type number struct {
+	kind reflect.Kind // reflect.Int64, reflect.Uint64 or reflect.Float64.
+	i    int64
+	u    uint64
+	f    float64
+}
+
+func intNumber(i int) number {
+	return number{kind: reflect.Int64, i: int64(i)}
+}
+
+// Return obj as a number, or false if it is not of an int, uint or float kind.
+func toNumber(obj interface{}) (number, bool) {
+	v := reflect.ValueOf(obj)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{kind: reflect.Int64, i: v.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return number{kind: reflect.Uint64, u: v.Uint()}, true
+	case reflect.Float32, reflect.Float64:
+		return number{kind: reflect.Float64, f: v.Float()}, true
+	}
+	return number{}, false
+}
+
+// Returned by compareBound for values that are not numbers, or NaN.
+const noBound = -2
+
+// Compare obj with the bound, returning -1, 0 or 1, or noBound.
+func compareBound(obj interface{}, bound number) int {
+	if n, ok := toNumber(obj); ok {
+		if cmp, ok := compareNumbers(n, bound); ok {
+			return cmp
+		}
+	}
+	return noBound
+}
+
+// Compare the numbers exactly, returning -1, 0 or 1, or false if one is NaN.
+func compareNumbers(a, b number) (int, bool) {
+	switch {
+	case a.kind == reflect.Float64 && b.kind == reflect.Float64:
+		if math.IsNaN(a.f) || math.IsNaN(b.f) {
+			return 0, false
+		}
+		return compareSign(a.f < b.f, a.f > b.f), true
+	case a.kind == reflect.Float64:
+		return compareFloatInteger(a.f, b)
+	case b.kind == reflect.Float64:
+		cmp, ok := compareFloatInteger(b.f, a)
+		return -cmp, ok
+	case a.kind == reflect.Int64 && b.kind == reflect.Int64:
+		return compareSign(a.i < b.i, a.i > b.i), true
+	case a.kind == reflect.Uint64 && b.kind == reflect.Uint64:
+		return compareSign(a.u < b.u, a.u > b.u), true
+	case a.kind == reflect.Int64:
+		if a.i < 0 {
+			return -1, true
+		}
+		return compareSign(uint64(a.i) < b.u, uint64(a.i) > b.u), true
+	}
+	if b.i < 0 {
+		return 1, true
+	}
+	return compareSign(a.u < uint64(b.i), a.u > uint64(b.i)), true
+}
+
+// Compare a float with an int or uint number exactly.
+func compareFloatInteger(f float64, n number) (int, bool) {
+	if math.IsNaN(f) {
+		return 0, false
+	}
+	whole := math.Trunc(f)
+	var cmp int
+	if n.kind == reflect.Int64 {
+		switch {
+		case whole < math.MinInt64:
+			return -1, true
+		case whole >= -math.MinInt64:
+			return 1, true
+		}
+		cmp = compareSign(int64(whole) < n.i, int64(whole) > n.i)
+	} else {
+		switch {
+		case whole < 0:
+			return -1, true
+		case whole >= 1<<64:
+			return 1, true
+		}
+		cmp = compareSign(uint64(whole) < n.u, uint64(whole) > n.u)
+	}
+	if cmp == 0 {
+		cmp = compareSign(f < whole, f > whole)
+	}
+	return cmp, true
+}
+
+func compareSign(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// Convert any int, uint or float kind to a float64 (approximately, for large
+// ints).  Returns false if obj is not a number.
+func toFloat(obj interface{}) (float64, bool) {
+	n, ok := toNumber(obj)
+	switch {
+	case !ok:
+		return 0, false
+	case n.kind == reflect.Int64:
+		return float64(n.i), true
+	case n.kind == reflect.Uint64:
+		return float64(n.u), true
+	}
+	return n.f, true
+}
+
 // Requires an array or string to be at least a given length.
 type MinSize struct {
 	Min int
@@ -105,6 +369,10 @@ func (m MinSize) DefaultMessage() string {
 	return fmt.Sprintln("Minimum size is", m.Min)
 }
 
+func (m MinSize) MessageKey() (string, []interface{}) {
+	return "validation.minsize", []interface{}{m.Min}
+}
+
 // Requires an array or string to be at most a given length.
 type MaxSize struct {
 	Max int
@@ -124,6 +392,10 @@ func (m MaxSize) DefaultMessage() string {
 	return fmt.Sprintln("Maximum size is", m.Max)
 }
 
+func (m MaxSize) MessageKey() (string, []interface{}) {
+	return "validation.maxsize", []interface{}{m.Max}
+}
+
 // Requires an array or string to be exactly a given length.
 type Length struct {
 	N int
@@ -143,6 +415,10 @@ func (s Length) DefaultMessage() string {
 	return fmt.Sprintln("Required length is", s.N)
 }
 
+func (s Length) MessageKey() (string, []interface{}) {
+	return "validation.length", []interface{}{s.N}
+}
+
 // Requires a string to match a given regex.
 type Match struct {
 	Regexp *regexp.Regexp
@@ -157,6 +433,10 @@ func (m Match) DefaultMessage() string {
 	return fmt.Sprintln("Must match", m.Regexp)
 }
 
+func (m Match) MessageKey() (string, []interface{}) {
+	return "validation.match", []interface{}{m.Regexp}
+}
+
 var emailPattern = regexp.MustCompile("[\\w!#$%&'*+/=?^_`{|}~-]+(?:\\.[\\w!#$%&'*+/=?^_`{|}~-]+)*@(?:[\\w](?:[\\w-]*[\\w])?\\.)+[a-zA-Z0-9](?:[\\w-]*[\\w])?")
 
 type Email struct {
@@ -166,3 +446,345 @@ type Email struct {
 func (e Email) DefaultMessage() string {
 	return fmt.Sprintln("Must be a valid email address")
 }
+
+func (e Email) MessageKey() (string, []interface{}) {
+	return "validation.email", nil
+}
+
+// Requires a string to be an absolute URL, with both a scheme and a host.
+// e.g. "http://www.example.com/path"
+type URL struct{}
+
+func (u URL) IsSatisfied(obj interface{}) bool {
+	str, ok := obj.(string)
+	if !ok {
+		return false
+	}
+	parsed, err := url.Parse(str)
+	return err == nil && parsed.Scheme != "" && parsed.Host != ""
+}
+
+func (u URL) DefaultMessage() string {
+	return fmt.Sprintln("Must be a valid URL")
+}
+
+func (u URL) MessageKey() (string, []interface{}) {
+	return "validation.url", nil
+}
+
+// Requires a string to be an IPv4 or IPv6 address.
+type IPAddr struct{}
+
+func (i IPAddr) IsSatisfied(obj interface{}) bool {
+	str, ok := obj.(string)
+	if !ok {
+		return false
+	}
+	return net.ParseIP(str) != nil
+}
+
+func (i IPAddr) DefaultMessage() string {
+	return fmt.Sprintln("Must be a valid IP address")
+}
+
+func (i IPAddr) MessageKey() (string, []interface{}) {
+	return "validation.ipaddr", nil
+}
+
+// Requires a time.Time to be no earlier than Min.
+type MinDate struct {
+	Min time.Time
+}
+
+func (m MinDate) IsSatisfied(obj interface{}) bool {
+	if t, ok := obj.(time.Time); ok {
+		return !t.Before(m.Min)
+	}
+	return false
+}
+
+func (m MinDate) DefaultMessage() string {
+	return fmt.Sprintln("Minimum date is", m.Min.Format(DateFormat))
+}
+
+func (m MinDate) MessageKey() (string, []interface{}) {
+	return "validation.mindate", []interface{}{m.Min.Format(DateFormat)}
+}
+
+// Requires a time.Time to be no later than Max.
+type MaxDate struct {
+	Max time.Time
+}
+
+func (m MaxDate) IsSatisfied(obj interface{}) bool {
+	if t, ok := obj.(time.Time); ok {
+		return !t.After(m.Max)
+	}
+	return false
+}
+
+func (m MaxDate) DefaultMessage() string {
+	return fmt.Sprintln("Maximum date is", m.Max.Format(DateFormat))
+}
+
+func (m MaxDate) MessageKey() (string, []interface{}) {
+	return "validation.maxdate", []interface{}{m.Max.Format(DateFormat)}
+}
+
+// Requires a time.Time to be within Min, Max inclusive.
+type DateRange struct {
+	MinDate
+	MaxDate
+}
+
+func (r DateRange) IsSatisfied(obj interface{}) bool {
+	return r.MinDate.IsSatisfied(obj) && r.MaxDate.IsSatisfied(obj)
+}
+
+func (r DateRange) DefaultMessage() string {
+	return fmt.Sprintln("Date range is", r.MinDate.Min.Format(DateFormat), "to", r.MaxDate.Max.Format(DateFormat))
+}
+
+func (r DateRange) MessageKey() (string, []interface{}) {
+	return "validation.daterange", []interface{}{r.MinDate.Min.Format(DateFormat), r.MaxDate.Max.Format(DateFormat)}
+}
+
+// Requires the value to be equal to one of the given Values.
+type In struct {
+	Values []interface{}
+}
+
+// Numbers of different kinds are equal if their values are, e.g. int64(1) and 1.
+func (in In) IsSatisfied(obj interface{}) bool {
+	for _, value := range in.Values {
+		if equalValues(obj, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (in In) DefaultMessage() string {
+	return fmt.Sprintln("Must be one of", in.Values)
+}
+
+func (in In) MessageKey() (string, []interface{}) {
+	return "validation.in", []interface{}{in.Values}
+}
+
+// Requires the value to be different from all of the given Values.
+type NotIn struct {
+	Values []interface{}
+}
+
+func (n NotIn) IsSatisfied(obj interface{}) bool {
+	return !In{n.Values}.IsSatisfied(obj)
+}
+
+func (n NotIn) DefaultMessage() string {
+	return fmt.Sprintln("Must not be one of", n.Values)
+}
+
+func (n NotIn) MessageKey() (string, []interface{}) {
+	return "validation.notin", []interface{}{n.Values}
+}
+
+// Requires a non-empty string made up of letters only.
+type Alpha struct{}
+
+func (a Alpha) IsSatisfied(obj interface{}) bool {
+	return isStringOf(obj, unicode.IsLetter)
+}
+
+func (a Alpha) DefaultMessage() string {
+	return fmt.Sprintln("Must contain only letters")
+}
+
+func (a Alpha) MessageKey() (string, []interface{}) {
+	return "validation.alpha", nil
+}
+
+// Requires a non-empty string made up of letters and digits only.
+type Alphanumeric struct{}
+
+func (a Alphanumeric) IsSatisfied(obj interface{}) bool {
+	return isStringOf(obj, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	})
+}
+
+func (a Alphanumeric) DefaultMessage() string {
+	return fmt.Sprintln("Must contain only letters and digits")
+}
+
+func (a Alphanumeric) MessageKey() (string, []interface{}) {
+	return "validation.alphanumeric", nil
+}
+
+// Returns true if obj is a non-empty string whose runes all satisfy the given func.
+func isStringOf(obj interface{}, f func(rune) bool) bool {
+	str, ok := obj.(string)
+	if !ok || str == "" {
+		return false
+	}
+	for _, r := range str {
+		if !f(r) {
+			return false
+		}
+	}
+	return true
+}
+
+var uuidPattern = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
+
+// Requires a string to be a UUID in its canonical, hyphenated form.
+// e.g. "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+type UUID struct{}
+
+func (u UUID) IsSatisfied(obj interface{}) bool {
+	str, ok := obj.(string)
+	if !ok {
+		return false
+	}
+	return uuidPattern.MatchString(str)
+}
+
+func (u UUID) DefaultMessage() string {
+	return fmt.Sprintln("Must be a valid UUID")
+}
+
+func (u UUID) MessageKey() (string, []interface{}) {
+	return "validation.uuid", nil
+}
+
+// Requires the value to be equal to another (sibling) value.
+// e.g. the password confirmation must equal the password.
+// Numbers of different kinds are equal if their values are, like for In.
+type EqualTo struct {
+	Other interface{}
+}
+
+func (e EqualTo) IsSatisfied(obj interface{}) bool {
+	return equalValues(obj, e.Other)
+}
+
+func (e EqualTo) DefaultMessage() string {
+	return fmt.Sprintln("Does not match")
+}
+
+func (e EqualTo) MessageKey() (string, []interface{}) {
+	return "validation.equalto", nil
+}
+
+// Requires the value to be different from another (sibling) value.
+type NotEqualTo struct {
+	Other interface{}
+}
+
+func (n NotEqualTo) IsSatisfied(obj interface{}) bool {
+	return !equalValues(obj, n.Other)
+}
+
+func (n NotEqualTo) DefaultMessage() string {
+	return fmt.Sprintln("Must be different")
+}
+
+func (n NotEqualTo) MessageKey() (string, []interface{}) {
+	return "validation.notequalto", nil
+}
+
+// Requires the value to be greater than another (sibling) value.
+// Numbers (of any kind), strings and times may be compared.
+// e.g. the end date must be after the start date.
+type GreaterThan struct {
+	Other interface{}
+}
+
+func (g GreaterThan) IsSatisfied(obj interface{}) bool {
+	result, ok := compareValues(obj, g.Other)
+	return ok && result > 0
+}
+
+func (g GreaterThan) DefaultMessage() string {
+	return fmt.Sprintln("Must be greater than", formatValue(g.Other))
+}
+
+func (g GreaterThan) MessageKey() (string, []interface{}) {
+	return "validation.greaterthan", []interface{}{formatValue(g.Other)}
+}
+
+// Requires the value to be less than another (sibling) value.
+// Numbers (of any kind), strings and times may be compared.
+type LessThan struct {
+	Other interface{}
+}
+
+func (l LessThan) IsSatisfied(obj interface{}) bool {
+	result, ok := compareValues(obj, l.Other)
+	return ok && result < 0
+}
+
+func (l LessThan) DefaultMessage() string {
+	return fmt.Sprintln("Must be less than", formatValue(l.Other))
+}
+
+func (l LessThan) MessageKey() (string, []interface{}) {
+	return "validation.lessthan", []interface{}{formatValue(l.Other)}
+}
+
+// Compare two numbers, strings or times.
+// Returns -1, 0 or 1 and true, or false if the values are not comparable.
+func compareValues(a, b interface{}) (int, bool) {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		switch {
+		case !ok:
+			return 0, false
+		case ta.Before(tb):
+			return -1, true
+		case ta.After(tb):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	if sa, ok := a.(string); ok {
+		sb, ok := b.(string)
+		switch {
+		case !ok:
+			return 0, false
+		case sa < sb:
+			return -1, true
+		case sa > sb:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	na, ok := toNumber(a)
+	if !ok {
+		return 0, false
+	}
+	nb, ok := toNumber(b)
+	if !ok {
+		return 0, false
+	}
+	return compareNumbers(na, nb)
+}
+
+// Return true if the values are equal: numbers of different kinds if their
+// values are, e.g. int64(1) and 1, and other values if they are deeply equal.
+func equalValues(a, b interface{}) bool {
+	if cmp, ok := compareValues(a, b); ok {
+		return cmp == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Format a value for use in a validation message.
+func formatValue(obj interface{}) string {
+	if t, ok := obj.(time.Time); ok {
+		return t.Format(DateFormat)
+	}
+	return fmt.Sprint(obj)
+}
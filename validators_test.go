@@ -0,0 +1,185 @@
+package revel
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type validatorCase struct {
+	validator Validator
+	obj       interface{}
+	expected  bool
+}
+
+var validatorCases = []validatorCase{
+	{Min{10}, 10, true},
+	{Min{10}, int8(9), false},
+	{Min{10}, uint16(11), true},
+	{Min{10}, 9.5, false},
+	{Min{10}, "11", false},
+	{Min{-1}, uint64(math.MaxUint64), true},
+	{MinFloat{1.5}, float32(1.25), false},
+	{MinFloat{1.5}, 1.75, true},
+	{MinFloat{1.5}, 2, true},
+	{Max{10}, int64(10), true},
+	{Max{10}, uint(11), false},
+	{Max{10}, "9", false},
+	{Max{10}, math.NaN(), false},
+	{MaxFloat{-1}, -1.5, true},
+	{Range{Min{1}, Max{3}}, 2, true},
+	{Range{Min{1}, Max{3}}, uint8(4), false},
+	{RangeFloat{MinFloat{0.5}, MaxFloat{1.5}}, 0.25, false},
+	{RangeFloat{MinFloat{0.5}, MaxFloat{1.5}}, 1, true},
+
+	{URL{}, "http://www.example.com/path?q=1", true},
+	{URL{}, "www.example.com", false},
+	{URL{}, "/relative/path", false},
+	{IPAddr{}, "127.0.0.1", true},
+	{IPAddr{}, "::1", true},
+	{IPAddr{}, "256.0.0.1", false},
+
+	{MinDate{time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)}, time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC), true},
+	{MinDate{time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)}, time.Date(2012, 12, 31, 0, 0, 0, 0, time.UTC), false},
+	{MaxDate{time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)}, time.Date(2013, 1, 2, 0, 0, 0, 0, time.UTC), false},
+	{MaxDate{time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)}, "2012-12-31", false},
+	{DateRange{
+		MinDate{time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)},
+		MaxDate{time.Date(2013, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}, time.Date(2013, 1, 15, 0, 0, 0, 0, time.UTC), true},
+
+	{In{[]interface{}{"red", "green"}}, "green", true},
+	{In{[]interface{}{"red", "green"}}, "blue", false},
+	{In{[]interface{}{1, 2, 3}}, 2, true},
+	{In{[]interface{}{1, 2, 3}}, int64(1), true},
+	{In{[]interface{}{1, 2, 3}}, uint8(3), true},
+	{In{[]interface{}{1, 2, 3}}, 2.5, false},
+	{In{[]interface{}{1, 2, 3}}, "1", false},
+	{In{[]interface{}{int64(1<<53 + 1)}}, uint64(1 << 53), false},
+	{NotIn{[]interface{}{0, 1}}, int32(1), false},
+	{NotIn{[]interface{}{"admin", "root"}}, "rob", true},
+	{NotIn{[]interface{}{"admin", "root"}}, "root", false},
+
+	{Alpha{}, "Revel", true},
+	{Alpha{}, "Revel1", false},
+	{Alpha{}, "", false},
+	{Alphanumeric{}, "Revel1", true},
+	{Alphanumeric{}, "Revel 1", false},
+
+	{UUID{}, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", true},
+	{UUID{}, "6ba7b8109dad11d180b400c04fd430c8", false},
+	{UUID{}, 12, false},
+}
+
+func TestValidators(t *testing.T) {
+	for _, c := range validatorCases {
+		if actual := c.validator.IsSatisfied(c.obj); actual != c.expected {
+			t.Errorf("%#v.IsSatisfied(%#v): expected %v, got %v", c.validator, c.obj, c.expected, actual)
+		}
+	}
+}
+
+func TestValidationBoundsOfAnyNumberKind(t *testing.T) {
+	v := &Validation{}
+	min, max := 16, int64(120)
+	if result := v.Range(18, min, max); !result.Ok {
+		t.Errorf("Expected 18 to be within the int bounds")
+	}
+	if result := v.Min(uint8(3), min); result.Ok {
+		t.Errorf("Expected 3 to be below the int minimum")
+	}
+	if result := v.Max(2.5, 2.5); !result.Ok {
+		t.Errorf("Expected 2.5 to be within the float maximum")
+	}
+	if result := v.Max(uint64(1<<63+1), uint64(1<<63)); result.Ok {
+		t.Errorf("Expected 2^63+1 to be above the uint maximum 2^63")
+	}
+}
+
+func TestValidationBoundMessageArgs(t *testing.T) {
+	// Whole bounds are ints, so that messages may use %d.
+	if _, args := (RangeFloat{MinFloat{5}, MaxFloat{10.0}}).MessageKey(); fmt.Sprintf("%d-%d", args...) != "5-10" {
+		t.Errorf("Expected int args for whole bounds, got %#v", args)
+	}
+	if _, args := (Range{Min{5}, Max{10}}).MessageKey(); fmt.Sprintf("%d-%d", args...) != "5-10" {
+		t.Errorf("Expected int args for int bounds, got %#v", args)
+	}
+	if _, args := (MaxFloat{2.5}).MessageKey(); args[0] != 2.5 {
+		t.Errorf("Expected a float arg for a fractional bound, got %#v", args)
+	}
+}
+
+func TestValidationMessagesAreLocalized(t *testing.T) {
+	loadMessages(testDataPath)
+	loadTestI18nConfig(t)
+
+	httpRequest, _ := http.NewRequest("GET", "/", nil)
+	request := NewRequest(httpRequest)
+	request.Locale = "nl"
+	v := &Validation{request: request}
+
+	if result := v.Required(""); result.Error.Message != "Verplicht" {
+		t.Errorf("Expected localized Required message, got '%s'", result.Error.Message)
+	}
+	if result := v.MinSize("ab", 3); result.Error.Message != "De minimale lengte is 3" {
+		t.Errorf("Expected localized MinSize message, got '%s'", result.Error.Message)
+	}
+	if result := v.Required("").MessageKey("user.name.required"); result.Error.Message != "Uw naam is verplicht" {
+		t.Errorf("Expected app-specific message, got '%s'", result.Error.Message)
+	}
+
+	// Keys that are not translated fall back to the default message.
+	if result := v.Email("not an email"); result.Error.Message != (Email{}).DefaultMessage() {
+		t.Errorf("Expected default Email message, got '%s'", result.Error.Message)
+	}
+	if result := v.Required("").MessageKey("unknown.key"); result.Error.Message != "Verplicht" {
+		t.Errorf("Expected message to be kept for an unknown key, got '%s'", result.Error.Message)
+	}
+}
+
+var crossFieldCases = []validatorCase{
+	{RequiredIf{true}, "", false},
+	{RequiredIf{true}, "10010", true},
+	{RequiredIf{false}, "", true},
+	{RequiredUnless{true}, "", true},
+	{RequiredUnless{false}, "", false},
+
+	{EqualTo{"secret"}, "secret", true},
+	{EqualTo{"secret"}, "Secret", false},
+	{NotEqualTo{"secret"}, "Secret", true},
+	{EqualTo{3}, int64(3), true},
+	{NotEqualTo{uint8(3)}, 3.0, false},
+	{EqualTo{int64(1<<53 + 1)}, float64(1 << 53), false},
+	{GreaterThan{time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)}, time.Date(2013, 1, 2, 0, 0, 0, 0, time.UTC), true},
+	{GreaterThan{time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)}, time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC), false},
+	{GreaterThan{5}, 5.5, true},
+	{GreaterThan{5}, "6", false},
+	{LessThan{uint(5)}, int8(-1), true},
+	{LessThan{"b"}, "a", true},
+	{LessThan{"b"}, "c", false},
+}
+
+func TestCrossFieldValidators(t *testing.T) {
+	for _, c := range crossFieldCases {
+		if actual := c.validator.IsSatisfied(c.obj); actual != c.expected {
+			t.Errorf("%#v.IsSatisfied(%#v): expected %v, got %v", c.validator, c.obj, c.expected, actual)
+		}
+	}
+}
+
+func TestValidationGroups(t *testing.T) {
+	v := &Validation{}
+	if v.InGroup("create") {
+		t.Error("Expected no validation group to be selected by default")
+	}
+
+	v.UseGroups("create", "admin")
+	if !v.InGroup("create") || !v.InGroup("update", "admin") {
+		t.Error("Expected the selected validation groups to be active")
+	}
+	if v.InGroup("update") {
+		t.Error("Expected group 'update' not to be active")
+	}
+}